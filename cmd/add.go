@@ -25,6 +25,12 @@ type KubeConfigOption struct {
 	config                *clientcmdapi.Config
 	fileName              string
 	insecureSkipTLSVerify bool
+	// nonInteractive skips the contexts whose name already exists instead of asking to rename them
+	nonInteractive bool
+	// source is recorded as the source of the added contexts
+	source *ContextSource
+	// sourceContexts maps the added contexts to their names in config
+	sourceContexts map[string]string
 }
 
 // Init AddCommand
@@ -72,6 +78,7 @@ func (ac *AddCommand) runAdd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var source *ContextSource
 	if file == "-" {
 		// from stdin
 		contents, err := io.ReadAll(os.Stdin)
@@ -82,23 +89,35 @@ func (ac *AddCommand) runAdd(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		source = fileSource(file, contents)
 	} else {
 		// check path
 		file, err := CheckAndTransformFilePath(file, cfgCreate)
 		if err != nil {
 			return err
 		}
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
 		newConfig, err = clientcmd.LoadFromFile(file)
 		if err != nil {
 			return err
 		}
+		source = fileSource(file, contents)
 	}
 
-	err = AddToLocal(newConfig, file, contextPrefix, cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
+	oldConfig, err := clientcmd.LoadFromFile(cfgFile)
 	if err != nil {
 		return err
 	}
-	return nil
+	kco := &KubeConfigOption{
+		config:                newConfig,
+		fileName:              getFileName(file),
+		insecureSkipTLSVerify: insecureSkipTLSVerify,
+		source:                source,
+	}
+	return kco.addToLocal(oldConfig, file, contextPrefix, cover, selectContext, contextTemplate, context)
 }
 
 // AddToLocal add kubeConfig to local
@@ -112,6 +131,11 @@ func AddToLocal(newConfig *clientcmdapi.Config, path, contextPrefix string, cove
 		fileName:              getFileName(path),
 		insecureSkipTLSVerify: insecureSkipTLSVerify,
 	}
+	return kco.addToLocal(oldConfig, path, contextPrefix, cover, selectContext, contextTemplate, context)
+}
+
+// addToLocal merge the kubeconfig of the option into oldConfig and write it
+func (kco *KubeConfigOption) addToLocal(oldConfig *clientcmdapi.Config, path, contextPrefix string, cover bool, selectContext bool, contextTemplate []string, context []string) error {
 	// merge context loop
 	outConfig, err := kco.handleContexts(oldConfig, contextPrefix, selectContext, contextTemplate, context)
 	if err != nil {
@@ -128,17 +152,58 @@ func AddToLocal(newConfig *clientcmdapi.Config, path, contextPrefix string, cove
 		return nil
 	}
 
-	if !cover {
+	if !cover && !dryRun {
 		cover, err = strconv.ParseBool(BoolUI(fmt.Sprintf("Does it overwrite File 「%s」?", cfgFile)))
 		if err != nil {
 			return err
 		}
 	}
-	err = WriteConfig(cover, path, outConfig)
-	if err != nil {
-		return err
+	if !cover && !dryRun {
+		return WriteConfig(cover, path, oldConfig, outConfig)
+	}
+	err = ApplyConfig(path, func(config *clientcmdapi.Config) error {
+		addEntries(config, oldConfig, outConfig)
+		return nil
+	})
+	if err == nil && kco.source != nil {
+		sources := make(map[string]*ContextSource)
+		for name := range outConfig.Contexts {
+			if _, ok := oldConfig.Contexts[name]; !ok {
+				sources[name] = kco.contextSource(name)
+			}
+		}
+		recordSources(cfgFile, sources)
+	}
+	return err
+}
+
+// contextSource return the source of the added context, with its name in the source kubeconfig
+func (kco *KubeConfigOption) contextSource(name string) *ContextSource {
+	source := *kco.source
+	source.Context = kco.sourceContexts[name]
+	return &source
+}
+
+// addEntries copy the contexts, clusters and users that outConfig added on top of oldConfig into config
+func addEntries(config, oldConfig, outConfig *clientcmdapi.Config) {
+	for key, obj := range outConfig.Clusters {
+		if _, ok := oldConfig.Clusters[key]; !ok {
+			config.Clusters[key] = obj
+		}
+	}
+	for key, obj := range outConfig.AuthInfos {
+		if _, ok := oldConfig.AuthInfos[key]; !ok {
+			config.AuthInfos[key] = obj
+		}
+	}
+	for key, obj := range outConfig.Contexts {
+		if _, ok := oldConfig.Contexts[key]; !ok {
+			config.Contexts[key] = obj
+		}
+	}
+	if outConfig.CurrentContext != oldConfig.CurrentContext {
+		config.CurrentContext = outConfig.CurrentContext
 	}
-	return nil
 }
 
 func (kc *KubeConfigOption) handleContexts(oldConfig *clientcmdapi.Config, contextPrefix string, selectContext bool, contextTemplate []string, context []string) (*clientcmdapi.Config, error) {
@@ -170,6 +235,10 @@ func (kc *KubeConfigOption) handleContexts(oldConfig *clientcmdapi.Config, conte
 			}
 		}
 
+		if kc.nonInteractive && checkContextName(newName, oldConfig) {
+			fmt.Printf("「%s」 Name already exists, skipped\n", newName)
+			continue
+		}
 		var quitNewName bool
 		for checkContextName(newName, oldConfig) {
 			nameConfirm := BoolUI(fmt.Sprintf("「%s」 Name already exists, do you want to rename it? (If you select `False`, this context will not be merged)", newName))
@@ -184,6 +253,10 @@ func (kc *KubeConfigOption) handleContexts(oldConfig *clientcmdapi.Config, conte
 		if quitNewName {
 			continue
 		}
+		if kc.sourceContexts == nil {
+			kc.sourceContexts = make(map[string]string)
+		}
+		kc.sourceContexts[newName] = name
 		itemConfig := kc.handleContext(oldConfig, newName, ctx)
 		newConfig = appendConfig(newConfig, itemConfig)
 		fmt.Printf("Add Context: %s \n", newName)
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// backupTimeFormat is the layout of a backup ID
+const backupTimeFormat = "20060102-150405.000000"
+
+// Backup kubeconfig snapshot taken before a write
+type Backup struct {
+	ID       string
+	Time     time.Time
+	Command  string
+	Contexts int
+	Path     string
+}
+
+// backupDir return the backup directory of the kubeconfig file
+func backupDir(file string) string {
+	return filepath.Join(kubecmHome(), "backup", pathHash(file))
+}
+
+// backupConfig snapshot the current content of file before it is overwritten
+func backupConfig(file string) error {
+	if backupLimit <= 0 {
+		return nil
+	}
+	// the private kubeconfig of kubecm shell is thrown away on exit
+	if os.Getenv(shellEnv) != "" && file == os.Getenv("KUBECONFIG") {
+		return nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	dir := backupDir(file)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	command := strings.ReplaceAll(cmdName, " ", "-")
+	if command == "" {
+		command = "kubecm"
+	}
+	name := fmt.Sprintf("%s_%s.yaml", time.Now().Format(backupTimeFormat), command)
+	if err = os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+		return err
+	}
+	return pruneBackups(file, backupLimit)
+}
+
+// pruneBackups remove the oldest backups, keeping at most limit of them
+func pruneBackups(file string, limit int) error {
+	backups, err := listBackups(file)
+	if err != nil {
+		return err
+	}
+	for i := limit; i < len(backups); i++ {
+		if err = os.Remove(backups[i].Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listBackups return the backups of the kubeconfig file, newest first
+func listBackups(file string) ([]Backup, error) {
+	dir := backupDir(file)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var backups []Backup
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || name == entry.Name() {
+			continue
+		}
+		id, command, _ := strings.Cut(name, "_")
+		t, err := time.ParseInLocation(backupTimeFormat, id, time.Local)
+		if err != nil {
+			continue
+		}
+		backup := Backup{
+			ID:      id,
+			Time:    t,
+			Command: strings.ReplaceAll(command, "-", " "),
+			Path:    filepath.Join(dir, entry.Name()),
+		}
+		if config, err := clientcmd.LoadFromFile(backup.Path); err == nil {
+			backup.Contexts = len(config.Contexts)
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// findBackup return the backup of the kubeconfig file with the given ID
+func findBackup(file, id string) (*Backup, error) {
+	backups, err := listBackups(file)
+	if err != nil {
+		return nil, err
+	}
+	for i := range backups {
+		if backups[i].ID == id {
+			return &backups[i], nil
+		}
+	}
+	return nil, errors.New("cannot find backup 「" + id + "」")
+}
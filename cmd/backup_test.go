@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func Test_backupConfig(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(appendMergeConfig, file); err != nil {
+		t.Fatal(err)
+	}
+	oldLimit, oldName := backupLimit, cmdName
+	defer func() { backupLimit, cmdName = oldLimit, oldName }()
+	backupLimit = 2
+	cmdName = "cloud add"
+
+	for i := 0; i < 3; i++ {
+		if err := backupConfig(file); err != nil {
+			t.Fatalf("backupConfig() error = %v", err)
+		}
+	}
+	backups, err := listBackups(file)
+	if err != nil {
+		t.Fatalf("listBackups() error = %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("listBackups() got %d backups, want 2", len(backups))
+	}
+	if !backups[0].Time.After(backups[1].Time) {
+		t.Errorf("listBackups() is not sorted newest first")
+	}
+	if backups[0].Command != "cloud add" || backups[0].Contexts != 2 {
+		t.Errorf("listBackups() got = %+v", backups[0])
+	}
+	got, err := findBackup(file, backups[1].ID)
+	if err != nil || got.Path != backups[1].Path {
+		t.Errorf("findBackup() got = %v, error = %v", got, err)
+	}
+	if _, err = findBackup(file, "not-exist"); err == nil {
+		t.Errorf("findBackup() want error")
+	}
+}
+
+func Test_backupConfigDisabled(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	oldLimit := backupLimit
+	defer func() { backupLimit = oldLimit }()
+
+	// a missing kubeconfig has nothing to back up
+	backupLimit = 10
+	if err := backupConfig(file); err != nil {
+		t.Fatalf("backupConfig() error = %v", err)
+	}
+	if err := os.WriteFile(file, []byte("apiVersion: v1\nkind: Config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	backupLimit = 0
+	if err := backupConfig(file); err != nil {
+		t.Fatalf("backupConfig() error = %v", err)
+	}
+	backups, _ := listBackups(file)
+	if len(backups) != 0 {
+		t.Errorf("listBackups() got %d backups, want 0", len(backups))
+	}
+}
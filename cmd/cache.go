@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/utils"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// cacheRefreshBackoff is how long a started background refresh keeps others from starting
+const cacheRefreshBackoff = 30 * time.Second
+
+// ClusterCache the cached status of the cluster of a context
+type ClusterCache struct {
+	Server    string         `json:"server"`
+	Host      string         `json:"host"`
+	Version   string         `json:"version"`
+	Summary   map[string]int `json:"summary,omitempty"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	// RefreshAt is when the latest background refresh was started
+	RefreshAt time.Time `json:"refreshAt,omitempty"`
+}
+
+// stale report whether the entry is older than ttl
+func (c *ClusterCache) stale(ttl time.Duration, now time.Time) bool {
+	return now.Sub(c.UpdatedAt) > ttl
+}
+
+// cacheFile return the cluster cache file of the kubeconfig file
+func cacheFile(file string) string {
+	return filepath.Join(kubecmHome(), "cache", pathHash(file)+".json")
+}
+
+// loadClusterCache return the cached cluster status of the contexts of the kubeconfig file
+func loadClusterCache(file string) (map[string]*ClusterCache, error) {
+	cache := make(map[string]*ClusterCache)
+	content, err := os.ReadFile(cacheFile(file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &cache); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %v", cacheFile(file), err)
+	}
+	return cache, nil
+}
+
+// updateClusterCache apply change to the cluster cache of the kubeconfig file under lock
+func updateClusterCache(file string, change func(cache map[string]*ClusterCache)) error {
+	path := cacheFile(file)
+	unlock, err := utils.LockFile(lockPath(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	cache, err := loadClusterCache(file)
+	if err != nil {
+		// the cache can always be rebuilt
+		cache = make(map[string]*ClusterCache)
+	}
+	change(cache)
+	content, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, content, 0600)
+}
+
+// cachedCluster return the cache entry of the context, nil when there is none for its current server
+// or it lacks the summary when withSummary
+func cachedCluster(file string, config *clientcmdapi.Config, context string, withSummary bool) *ClusterCache {
+	cache, err := loadClusterCache(file)
+	if err != nil {
+		return nil
+	}
+	entry, ok := cache[context]
+	if !ok || entry.Server != contextServer(config, context) || (withSummary && entry.Summary == nil) {
+		return nil
+	}
+	return entry
+}
+
+// storeClusterStatus cache the status and summary of the cluster of the context
+func storeClusterStatus(file string, config *clientcmdapi.Config, context string, status *ClusterStatusCheck, summary map[string]int) error {
+	return updateClusterCache(file, func(cache map[string]*ClusterCache) {
+		cache[context] = &ClusterCache{
+			Server:    contextServer(config, context),
+			Host:      status.Config.Host,
+			Version:   status.Version.GitVersion,
+			Summary:   summary,
+			UpdatedAt: time.Now(),
+		}
+	})
+}
+
+// refreshClusterCache fetch the status of the cluster of the context, and its summary when withSummary, and cache it
+func refreshClusterCache(file string, config *clientcmdapi.Config, context string, withSummary bool) error {
+	status, err := contextClusterStatus(config, context, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	var summary map[string]int
+	if withSummary {
+		if summary, err = clusterSummary(status.ClientSet); err != nil {
+			return err
+		}
+	}
+	return storeClusterStatus(file, config, context, status, summary)
+}
+
+// startCacheRefresh refresh the cache entry of the current context in a detached kubecm process,
+// unless another refresh was started recently
+func startCacheRefresh(file, context string, withSummary bool) error {
+	start := false
+	err := updateClusterCache(file, func(cache map[string]*ClusterCache) {
+		entry, ok := cache[context]
+		if !ok || time.Since(entry.RefreshAt) < cacheRefreshBackoff {
+			return
+		}
+		entry.RefreshAt = time.Now()
+		start = true
+	})
+	if err != nil || !start {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return utils.StartDetached(exec.Command(exe, "list", "--config", file, "--refresh-cache", fmt.Sprintf("--summary=%t", withSummary)))
+}
+
+// contextServer return the server of the cluster of the context
+func contextServer(config *clientcmdapi.Config, context string) string {
+	ctx, ok := config.Contexts[context]
+	if !ok {
+		return ""
+	}
+	cluster, ok := config.Clusters[ctx.Cluster]
+	if !ok {
+		return ""
+	}
+	return cluster.Server
+}
+
+// printClusterCache print the cached cluster status like the live one, marking its age
+func printClusterCache(out io.Writer, entry *ClusterCache, ttl time.Duration, now time.Time, withSummary bool) {
+	age := now.Sub(entry.UpdatedAt).Round(time.Second)
+	printString(out, "Cluster check succeeded! ")
+	if entry.stale(ttl, now) {
+		printYellow(out, fmt.Sprintf("(stale, cached %s ago, refreshing in the background)", age))
+	} else {
+		printString(out, fmt.Sprintf("(cached %s ago)", age))
+	}
+	printString(out, "\nKubernetes version ")
+	printYellow(out, entry.Version)
+	printService(out, "\nKubernetes master", entry.Host)
+	if withSummary && entry.Summary != nil {
+		printKV(out, "[Summary] ", entry.Summary)
+	}
+}
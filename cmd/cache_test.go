@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	v "k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/rest"
+)
+
+func Test_clusterCache(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := "cache-config"
+	config := appendMergeConfig.DeepCopy()
+	if got := cachedCluster(file, config, "root-context", true); got != nil {
+		t.Errorf("cachedCluster() got = %v without cache", got)
+	}
+	status := &ClusterStatusCheck{Version: &v.Info{GitVersion: "v1.30.1"}, Config: &rest.Config{Host: "http://pig.org:8080"}}
+	summary := map[string]int{"Namespace": 3, "Node": 1, "Pod": 10}
+	if err := storeClusterStatus(file, config, "root-context", status, summary); err != nil {
+		t.Fatalf("storeClusterStatus() error = %v", err)
+	}
+	entry := cachedCluster(file, config, "root-context", true)
+	if entry == nil || entry.Version != "v1.30.1" || entry.Summary["Pod"] != 10 {
+		t.Fatalf("cachedCluster() got = %v", entry)
+	}
+	if got := cachedCluster(file, config, "federal-context", true); got != nil {
+		t.Errorf("cachedCluster() got = %v for another context", got)
+	}
+
+	now := entry.UpdatedAt.Add(time.Minute)
+	if entry.stale(5*time.Minute, now) || !entry.stale(30*time.Second, now) {
+		t.Errorf("stale() is wrong for an entry of 1m")
+	}
+	out := new(bytes.Buffer)
+	printClusterCache(out, entry, 30*time.Second, now, true)
+	for _, want := range []string{"stale, cached 1m0s ago", "v1.30.1", "http://pig.org:8080", "Pod"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printClusterCache() got = %q, want %q", out.String(), want)
+		}
+	}
+
+	// the entry is dropped once the context points to another server
+	config.Clusters["pig-cluster"].Server = "http://new-pig.org:8080"
+	if got := cachedCluster(file, config, "root-context", true); got != nil {
+		t.Errorf("cachedCluster() got = %v after the server changed", got)
+	}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bndr/gotabulate"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// certExpiryEnv is the number of days before expiry when list and switch start to warn, 0 disables the warning
+	certExpiryEnv = "KUBECM_CERT_EXPIRY_DAYS"
+	// defaultCertExpiryDays is the warning window when certExpiryEnv is not set
+	defaultCertExpiryDays = 30
+)
+
+// CertInfo certificate record of kubecm certs
+type CertInfo struct {
+	Kind     string    `json:"kind"`
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"`
+}
+
+// CertsCommand certs cmd struct
+type CertsCommand struct {
+	BaseCommand
+}
+
+// Init CertsCommand
+func (cc *CertsCommand) Init() {
+	cc.command = &cobra.Command{
+		Use:   "certs",
+		Short: "Show the expiry of client certificates and cluster CAs",
+		Long: `
+Show the expiry of client certificates and cluster CAs, the most urgent first.
+list and switch warn when the client certificate of the context expires within
+KUBECM_CERT_EXPIRY_DAYS days (default 30, 0 disables the warning).
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cc.runCerts(cmd, args)
+		},
+		Example: certsExample(),
+	}
+	cc.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml")
+	cc.AddCommands(&DocsCommand{})
+}
+
+func (cc *CertsCommand) runCerts(cmd *cobra.Command, args []string) error {
+	output, _ := cc.command.Flags().GetString("output")
+	if output != "" && output != OutputJSON && output != OutputYAML {
+		return fmt.Errorf("unsupported output format %q, the available values are: json, yaml", output)
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	infos := certInfos(config, filepath.Dir(cfgFile), time.Now())
+	if len(infos) == 0 {
+		return errors.New("no certificate found")
+	}
+	if output != "" {
+		return printStructured(os.Stdout, output, infos)
+	}
+	return printCertInfos(os.Stdout, infos)
+}
+
+// certInfos return the client certificates and cluster CAs of config, the earliest expiry first.
+// Certificates that can not be read or parsed are skipped, kubecm doctor reports them.
+func certInfos(config *clientcmdapi.Config, dir string, now time.Time) []CertInfo {
+	var infos []CertInfo
+	add := func(kind, name string, certs []*x509.Certificate) {
+		for _, cert := range certs {
+			infos = append(infos, CertInfo{
+				Kind:     kind,
+				Name:     name,
+				Subject:  cert.Subject.String(),
+				Issuer:   cert.Issuer.String(),
+				NotAfter: cert.NotAfter,
+				DaysLeft: daysLeft(cert.NotAfter, now),
+			})
+		}
+	}
+	for name, authInfo := range config.AuthInfos {
+		if cert, err := clientCertificate(authInfo, dir); err == nil && cert != nil {
+			add("user", name, []*x509.Certificate{cert})
+		}
+	}
+	for name, cluster := range config.Clusters {
+		data := cluster.CertificateAuthorityData
+		if len(data) == 0 && cluster.CertificateAuthority != "" {
+			data, _ = readConfigFile(cluster.CertificateAuthority, dir)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if certs, err := parseCertificates(data); err == nil {
+			add("cluster", name, certs)
+		}
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		if !infos[i].NotAfter.Equal(infos[j].NotAfter) {
+			return infos[i].NotAfter.Before(infos[j].NotAfter)
+		}
+		if infos[i].Kind != infos[j].Kind {
+			return infos[i].Kind > infos[j].Kind
+		}
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// clientCertificate return the client certificate of the user, nil when it has none
+func clientCertificate(authInfo *clientcmdapi.AuthInfo, dir string) (*x509.Certificate, error) {
+	data := authInfo.ClientCertificateData
+	if len(data) == 0 && authInfo.ClientCertificate != "" {
+		var err error
+		if data, err = readConfigFile(authInfo.ClientCertificate, dir); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// daysLeft return the whole days until notAfter, negative once expired for a day
+func daysLeft(notAfter, now time.Time) int {
+	return int(notAfter.Sub(now).Hours() / 24)
+}
+
+// certExpiryDays return the warning window of KUBECM_CERT_EXPIRY_DAYS
+func certExpiryDays() int {
+	days, err := strconv.Atoi(os.Getenv(certExpiryEnv))
+	if err != nil || days < 0 {
+		return defaultCertExpiryDays
+	}
+	return days
+}
+
+// certExpiryWarning return a warning when the client certificate of the context expires within the window
+func certExpiryWarning(config *clientcmdapi.Config, context, dir string, now time.Time) string {
+	window := certExpiryDays()
+	ctx, ok := config.Contexts[context]
+	if window == 0 || !ok {
+		return ""
+	}
+	authInfo, ok := config.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return ""
+	}
+	cert, err := clientCertificate(authInfo, dir)
+	if err != nil || cert == nil {
+		return ""
+	}
+	days := daysLeft(cert.NotAfter, now)
+	switch {
+	case days >= window:
+		return ""
+	case now.After(cert.NotAfter):
+		return fmt.Sprintf("WARNING: the client certificate of context 「%s」 expired at %s, run `kubecm certs` for details\n",
+			context, cert.NotAfter.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("WARNING: the client certificate of context 「%s」 expires in %d days at %s, run `kubecm certs` for details\n",
+		context, days, cert.NotAfter.Format("2006-01-02 15:04:05"))
+}
+
+// printCertExpiryWarning print the expiry warning of the context of cfgFile in yellow, if any
+func printCertExpiryWarning(out io.Writer, config *clientcmdapi.Config, context string) {
+	if warning := certExpiryWarning(config, context, filepath.Dir(cfgFile), time.Now()); warning != "" {
+		printYellow(out, warning)
+	}
+}
+
+func printCertInfos(out io.Writer, infos []CertInfo) error {
+	var table [][]string
+	for _, info := range infos {
+		table = append(table, []string{info.Kind, info.Name, info.Subject, info.Issuer,
+			info.NotAfter.Format("2006-01-02 15:04:05"), strconv.Itoa(info.DaysLeft)})
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"KIND", "NAME", "SUBJECT", "ISSUER", "NOT AFTER", "DAYS LEFT"})
+	tabulate.SetWrapStrings(true)
+	tabulate.SetMaxCellSize(40)
+	tabulate.SetWrapDelimiter(',')
+	tabulate.SetAlign("left")
+	fmt.Fprintln(out, tabulate.Render("grid", "left"))
+	return nil
+}
+
+func certsExample() string {
+	return `
+# Show the expiry of client certificates and cluster CAs
+kubecm certs
+# Output as json
+kubecm certs -o json
+# Warn in list and switch when the client certificate expires within 60 days
+export KUBECM_CERT_EXPIRY_DAYS=60
+`
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_certInfos(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+	soonCert, _ := newTestCertificate(t, "soon", now.Add(10*24*time.Hour+time.Hour))
+	laterCert, _ := newTestCertificate(t, "later", now.Add(100*24*time.Hour+time.Hour))
+	expiredCert, _ := newTestCertificate(t, "expired", now.Add(-2*24*time.Hour))
+	if err := os.WriteFile(filepath.Join(dir, "later.crt"), laterCert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := &clientcmdapi.Config{
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"soon-user":    {ClientCertificateData: soonCert},
+			"file-user":    {ClientCertificate: "later.crt"},
+			"token-user":   {Token: "token"},
+			"invalid-user": {ClientCertificateData: []byte("not a pem")},
+		},
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"old-cluster": {Server: "https://old.org", CertificateAuthorityData: expiredCert},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"soon-context":  {AuthInfo: "soon-user", Cluster: "old-cluster"},
+			"later-context": {AuthInfo: "file-user", Cluster: "old-cluster"},
+			"token-context": {AuthInfo: "token-user", Cluster: "old-cluster"},
+		},
+	}
+	infos := certInfos(config, dir, now)
+	want := []struct {
+		name     string
+		subject  string
+		daysLeft int
+	}{
+		{"old-cluster", "CN=expired", -2},
+		{"soon-user", "CN=soon", 10},
+		{"file-user", "CN=later", 100},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("certInfos() got = %v, want %v", infos, want)
+	}
+	for i, info := range infos {
+		if info.Name != want[i].name || info.Subject != want[i].subject || info.DaysLeft != want[i].daysLeft {
+			t.Errorf("certInfos() got = %v, want %v", info, want[i])
+		}
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		days    string
+		want    string
+	}{
+		{"within-window", "soon-context", "", "expires in 10 days"},
+		{"outside-window", "later-context", "", ""},
+		{"custom-window", "later-context", "120", "expires in 100 days"},
+		{"disabled", "soon-context", "0", ""},
+		{"no-certificate", "token-context", "", ""},
+		{"missing-context", "missing-context", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(certExpiryEnv, tt.days)
+			got := certExpiryWarning(config, tt.context, dir, now)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("certExpiryWarning() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 // ClearCommand clean command struct
@@ -49,20 +51,35 @@ func (cl *ClearCommand) runClear(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// errNothingToClear stop the update when there is nothing to clean
+var errNothingToClear = errors.New("nothing to clear")
+
 func clearContext(file string) (bool, error) {
-	config, err := clientcmd.LoadFromFile(file)
+	_, err := clientcmd.LoadFromFile(file)
 	if err != nil {
 		return false, err
 	}
-	outConfig := config.DeepCopy()
-	outConfig = CheckValidContext(true, outConfig)
-	if reflect.DeepEqual(config, outConfig) {
+	var removed []string
+	err = UpdateConfigFile(file, func(config *clientcmdapi.Config) error {
+		outConfig := CheckValidContext(true, config.DeepCopy())
+		if reflect.DeepEqual(config, outConfig) {
+			return errNothingToClear
+		}
+		removed = removedContexts(config, outConfig)
+		*config = *outConfig
+		return nil
+	})
+	if errors.Is(err, errNothingToClear) {
 		return true, nil
 	}
-	err = UpdateConfigFile(file, outConfig)
 	if err != nil {
 		return false, err
 	}
+	if len(removed) > 0 && !dryRun {
+		if err = deleteMetadata(file, removed...); err != nil {
+			printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to delete the tags of the contexts: %v\n", err))
+		}
+	}
 	return false, nil
 }
 
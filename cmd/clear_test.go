@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 func Test_clearContext(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
 	trueFile, _ := os.CreateTemp("", "")
 	falseFile, _ := os.CreateTemp("", "")
 	defer os.Remove(trueFile.Name())
@@ -41,3 +43,29 @@ func Test_clearContext(t *testing.T) {
 		})
 	}
 }
+
+func Test_clearContext_metadata(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(wrongRootConfig, file); err != nil {
+		t.Fatal(err)
+	}
+	err := updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		metadata["root-context"] = &ContextMetadata{Tags: map[string]string{protectedTag: ""}}
+		metadata["federal-context"] = &ContextMetadata{Tags: map[string]string{"env": "dev"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = clearContext(file); err != nil {
+		t.Fatalf("clearContext() error = %v", err)
+	}
+	metadata, err := loadMetadata(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := metadata["root-context"]; ok || metadata["federal-context"] == nil {
+		t.Errorf("clearContext() left the metadata %v, want the one of root-context deleted", metadata)
+	}
+}
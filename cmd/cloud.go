@@ -1,10 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/BussanQ/kubecm/pkg/cloud"
 	"github.com/manifoldco/promptui"
@@ -16,48 +17,6 @@ type CloudCommand struct {
 	BaseCommand
 }
 
-// CloudInfo Public cloud info
-type CloudInfo struct {
-	Name     string
-	Alias    []string
-	HomePage string
-	Service  string
-}
-
-// Clouds date of clouds
-var Clouds = []CloudInfo{
-	{
-		Name:     "AlibabaCloud",
-		Alias:    []string{"alibabacloud", "alicloud", "aliyun", "ack"},
-		HomePage: "https://cs.console.aliyun.com",
-		Service:  "ACK",
-	},
-	{
-		Name:     "TencentCloud",
-		Alias:    []string{"tencentcloud", "tencent", "tke"},
-		HomePage: "https://console.cloud.tencent.com/tke",
-		Service:  "TKE",
-	},
-	{
-		Name:     "Rancher",
-		Alias:    []string{"rancher"},
-		HomePage: "https://rancher.com",
-		Service:  "Rancher",
-	},
-	{
-		Name:     "AWS",
-		Alias:    []string{"aws", "eks"},
-		HomePage: "https://console.aws.amazon.com/eks/home",
-		Service:  "EKS",
-	},
-	{
-		Name:     "Azure",
-		Alias:    []string{"azure", "aks"},
-		HomePage: "https://portal.azure.com",
-		Service:  "AKS",
-	},
-}
-
 // Init CloudCommand
 func (cc *CloudCommand) Init() {
 	cc.command = &cobra.Command{
@@ -68,185 +27,157 @@ func (cc *CloudCommand) Init() {
 	cc.command.PersistentFlags().String("provider", "", "public cloud")
 	cc.command.PersistentFlags().String("cluster_id", "", "kubernetes cluster id")
 	cc.command.PersistentFlags().String("region_id", "", "cloud region id")
+	cc.command.PersistentFlags().String("profile", "", "AWS profile of the shared config to authenticate with")
+	cc.command.PersistentFlags().String("role-arn", "", "AWS role assumed to list the clusters and get their token")
+	cc.command.PersistentFlags().String("external-id", "", "external id of the AWS role of --role-arn, not supported by the kubeconfigs of cloud add and sync")
+	cc.command.PersistentFlags().Bool("non-interactive", false, "never prompt, fail when a credential, region or cluster is not given by env var or flag, on by default when stdin is not a terminal")
 	cc.AddCommands(&CloudAddCommand{})
 	cc.AddCommands(&CloudListCommand{})
+	cc.AddCommands(&CloudSyncCommand{})
 	cc.AddCommands(&DocsCommand{})
 }
 
-func getClusters(provider, regionID string, num int) ([]cloud.ClusterInfo, error) {
-	var clusters []cloud.ClusterInfo
-	var err error
-	switch num {
-	case -1:
-		var allAlias []string
-		for _, cloudInfo := range Clouds {
-			allAlias = append(allAlias, cloudInfo.Alias...)
-		}
-		return nil, fmt.Errorf("'%s' is not supported, supported cloud alias are %v", provider, allAlias)
-	case 0:
-		accessKeyID, accessKeySecret := checkEnvForSecret(0)
-		ali := cloud.AliCloud{
-			AccessKeyID:     accessKeyID,
-			AccessKeySecret: accessKeySecret,
-		}
-		clusters, err = ali.ListCluster()
-		if err != nil {
-			return nil, err
-		}
-	case 1:
-		secretID, secretKey := checkEnvForSecret(1)
-		ten := cloud.TencentCloud{
-			SecretID:  secretID,
-			SecretKey: secretKey,
-		}
-		if regionID == "" {
-			regionList, err := ten.GetRegionID()
-			if err != nil {
-				return nil, err
-			}
-			regionNum := selectRegion(regionList, "Select Region Name")
-			ten.RegionID = regionList[regionNum]
-		} else {
-			ten.RegionID = regionID
-		}
-		clusters, err = ten.ListCluster()
-		if err != nil {
-			return nil, err
-		}
-	case 2:
-		fmt.Println("⛅  Selected: Rancher")
-		serverURL, apiKey := checkEnvForSecret(2)
-		rancher := cloud.Rancher{
-			ServerURL: serverURL,
-			APIKey:    apiKey,
-		}
-		clusters, err = rancher.ListCluster()
-		if err != nil {
-			return nil, err
-		}
-	case 3:
-		fmt.Println("⛅  Selected: AWS")
-		accessKeyID, accessKeySecret := checkEnvForSecret(3)
-		aws := cloud.AWS{
-			AccessKeyID:     accessKeyID,
-			AccessKeySecret: accessKeySecret,
-		}
-		if regionID == "" {
-			regionList, err := cloud.GetRegionID()
-			if err != nil {
-				return nil, err
-			}
-			regionNum := selectRegion(regionList, "Select Region ID")
-			aws.RegionID = regionList[regionNum]
-		} else {
-			aws.RegionID = regionID
-		}
-		clusters, err = aws.ListCluster()
-		if err != nil {
-			return nil, err
-		}
-	case 4:
-		fmt.Println("⛅  Selected: Azure")
-		authModes := []string{"Default (SDK Auth)", "Service Principal"}
-		authMode := selectOption(nil, authModes, "Select Auth Type")
-		azure := cloud.Azure{
-			AuthMode:       cloud.AzureAuth(authMode),
-			TenantID:       os.Getenv("AZURE_TENANT_ID"),
-			SubscriptionID: os.Getenv("AZURE_SUBSCRIPTION_ID"),
-		}
+// promptPrompter asks for the input of the cloud providers with promptui
+type promptPrompter struct{}
 
-		if azure.TenantID == "" {
-			azure.TenantID = PromptUI("Azure Tenant ID", "")
-		}
+func (promptPrompter) Input(label string) (string, error) {
+	return PromptUI(label, ""), nil
+}
 
-		if azure.AuthMode == cloud.AuthModeServicePrincipal {
-			azure.ClientID, azure.ClientSecret = checkEnvForSecret(4)
-			azure.ObjectID = os.Getenv("AZURE_OBJECT_ID")
+func (promptPrompter) Select(label string, options []string) (int, error) {
+	return selectOption(nil, options, label), nil
+}
 
-			if azure.ObjectID == "" {
-				azure.ObjectID = PromptUI("Azure Object ID", "")
-			}
-		}
+// cloudPrompter return the prompter of the cloud providers, nil in non-interactive mode
+func cloudPrompter(cmd *cobra.Command) cloud.Prompter {
+	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+	if nonInteractive || !stdinIsTerminal() {
+		return nil
+	}
+	return promptPrompter{}
+}
 
-		subscriptionList, err := azure.ListSubscriptions()
-		if err != nil {
-			return nil, err
-		}
+// cloudSession return the session of the --region_id, --profile, --role-arn and --external-id flags
+func cloudSession(cmd *cobra.Command) *cloud.Session {
+	regionID, _ := cmd.Flags().GetString("region_id")
+	profile, _ := cmd.Flags().GetString("profile")
+	roleARN, _ := cmd.Flags().GetString("role-arn")
+	externalID, _ := cmd.Flags().GetString("external-id")
+	return &cloud.Session{
+		RegionID:   regionID,
+		Prompter:   cloudPrompter(cmd),
+		Profile:    profile,
+		RoleARN:    roleARN,
+		ExternalID: externalID,
+	}
+}
 
-		for _, subscription := range subscriptionList {
-			if azure.SubscriptionID != "" && azure.SubscriptionID != subscription.ID {
-				continue
-			}
+// unsupportedProviderError the alias of the --provider flag is not supported
+type unsupportedProviderError struct {
+	alias string
+}
 
-			subscriptionClusters, err := azure.ListCluster(subscription)
-			if err != nil {
-				return nil, err
-			}
-			clusters = append(clusters, subscriptionClusters...)
+func (e *unsupportedProviderError) Error() string {
+	return fmt.Sprintf("'%s' is not supported, supported cloud alias are %v", e.alias, cloud.Aliases())
+}
+
+// getProvider return the provider of the alias of the --provider flag, or the selected one when it is empty
+func getProvider(alias string, prompter cloud.Prompter) (*cloud.Provider, error) {
+	var provider *cloud.Provider
+	switch {
+	case alias != "":
+		if provider = cloud.LookupProvider(alias); provider == nil {
+			return nil, &unsupportedProviderError{alias: alias}
 		}
+	case prompter == nil:
+		return nil, &cloud.MissingInputError{Flags: []string{"provider"}}
+	default:
+		provider = cloud.Providers[selectCloud(cloud.Providers, "Select Cloud")]
 	}
+	fmt.Printf("⛅  Selected: %s\n", provider.Name)
+	return provider, nil
+}
 
-	return clusters, err
+// newCloudClient create the client of the provider in the session with its credentials. The client of
+// a regional provider is created again in the selected region when the region of the session is empty,
+// so New of those must not prompt, the selected region is set in the session.
+// A nil prompter fails instead of prompting.
+func newCloudClient(provider *cloud.Provider, session *cloud.Session) (cloud.Cluster, error) {
+	if err := session.Resolve(provider.Credentials...); err != nil {
+		return nil, err
+	}
+	client, err := provider.New(session)
+	if err != nil || !provider.Regional || session.RegionID != "" {
+		return client, err
+	}
+	if err = selectRegion(provider, client, session); err != nil {
+		return nil, err
+	}
+	return provider.New(session)
 }
 
-func checkEnvForSecret(num int) (string, string) {
-	switch num {
-	case 0:
-		accessKeyID, id := os.LookupEnv("ACCESS_KEY_ID")
-		accessKeySecret, sec := os.LookupEnv("ACCESS_KEY_SECRET")
-		if !id || !sec {
-			accessKeyID = PromptUI("AlibabaCloud Access Key ID", "")
-			accessKeySecret = PromptUI("AlibabaCloud Access Key Secret", "")
-		}
-		return accessKeyID, accessKeySecret
-	case 1:
-		secretID, id := os.LookupEnv("TENCENTCLOUD_SECRET_ID")
-		secretKey, key := os.LookupEnv("TENCENTCLOUD_SECRET_KEY")
-		if !id || !key {
-			secretID = PromptUI("TencentCloud API secretId", "")
-			secretKey = PromptUI("TencentCloud API secretKey", "")
-		}
-		return secretID, secretKey
-	case 2:
-		serverURL, su := os.LookupEnv("RANCHER_SERVER_URL")
-		apiKey, key := os.LookupEnv("RANCHER_API_KEY")
-		if !su || !key {
-			serverURL = PromptUI("Rancher API serverURL", "")
-			apiKey = PromptUI("Rancher API key", "")
-		}
-		return serverURL, apiKey
-	case 3:
-		accessKeyID, id := os.LookupEnv("AWS_ACCESS_KEY_ID")
-		accessKeySecret, key := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
-		if !id || !key {
-			accessKeyID = PromptUI("AWS Access Key ID", "")
-			accessKeySecret = PromptUI("AWS Access Key Secret", "")
-		}
-		return accessKeyID, accessKeySecret
-	case 4:
-		accessKeyID, id := os.LookupEnv("AZURE_CLIENT_ID")
-		accessKeySecret, key := os.LookupEnv("AZURE_CLIENT_SECRET")
-		if !id || !key {
-			accessKeyID = PromptUI("Azure Client ID", "")
-			accessKeySecret = PromptUI("Azure Client Secret", "")
-		}
-		return accessKeyID, accessKeySecret
+// selectRegion set the region of the session to the one selected from the regions of the client.
+// A nil prompter fails instead of prompting.
+func selectRegion(provider *cloud.Provider, client cloud.Cluster, session *cloud.Session) error {
+	if session.Prompter == nil {
+		return &cloud.MissingInputError{Flags: []string{"region_id"}}
 	}
-	return "", ""
+	regionList, err := client.GetRegionID()
+	if err != nil {
+		return err
+	}
+	if len(regionList) == 0 {
+		return fmt.Errorf("no regions found for %s", provider.Name)
+	}
+	label := provider.RegionPrompt
+	if label == "" {
+		label = "Select Region ID"
+	}
+	regionNum, err := session.Prompter.Select(label, regionList)
+	if err != nil {
+		return err
+	}
+	session.RegionID = regionList[regionNum]
+	return nil
 }
 
-func checkFlags(provider string) int {
-	for i, cloud := range Clouds {
-		for _, alias := range cloud.Alias {
-			if alias == provider {
-				return i
-			}
-		}
+// clusterByID return the cluster of --cluster_id, the region is selected when the id needs one and
+// --region_id is not set
+func clusterByID(provider *cloud.Provider, client cloud.Cluster, session *cloud.Session, clusterID string) (cloud.ClusterInfo, error) {
+	cluster, err := provider.ClusterByID(clusterID, session.RegionID)
+	var missing *cloud.MissingInputError
+	if !errors.As(err, &missing) || session.Prompter == nil {
+		return cluster, err
+	}
+	if err = selectRegion(provider, client, session); err != nil {
+		return cloud.ClusterInfo{}, err
 	}
-	return -1
+	return provider.ClusterByID(clusterID, session.RegionID)
 }
 
-func selectCloud(clouds []CloudInfo, label string) int {
+// cloudSource return the source of the contexts added from the cluster
+func cloudSource(provider *cloud.Provider, cluster cloud.ClusterInfo, session *cloud.Session) *ContextSource {
+	return &ContextSource{
+		Provider:   provider.Alias[0],
+		Account:    cluster.Account,
+		RegionID:   cluster.RegionID,
+		ClusterID:  cluster.ID,
+		Profile:    session.Profile,
+		RoleARN:    session.RoleARN,
+		ExternalID: session.ExternalID,
+		ImportedAt: time.Now(),
+	}
+}
+
+func getClusters(provider *cloud.Provider, session *cloud.Session) ([]cloud.ClusterInfo, error) {
+	client, err := newCloudClient(provider, session)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListCluster()
+}
+
+func selectCloud(clouds []*cloud.Provider, label string) int {
 	templates := &promptui.SelectTemplates{
 		Label:    "{{ . }}",
 		Active:   "\U0001F680 {{ .Name | red }}",
@@ -300,17 +231,6 @@ func selectCluster(clouds []cloud.ClusterInfo, label string) int {
 	return i
 }
 
-func selectRegion(regionList []string, label string) int {
-	templates := &promptui.SelectTemplates{
-		Label:    "{{ . }}",
-		Active:   "\U0001F680 {{ .Name | red }}",
-		Inactive: "  {{ . | cyan }}",
-		Selected: "\U0001F30D Selected: {{ . | green }}",
-	}
-
-	return selectOption(templates, regionList, label)
-}
-
 func selectOption(templates *promptui.SelectTemplates, options []string, label string) int {
 	if templates == nil {
 		templates = &promptui.SelectTemplates{
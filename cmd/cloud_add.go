@@ -3,12 +3,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+
 	"github.com/BussanQ/kubecm/pkg/cloud"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
-	"os"
-	"strings"
 )
 
 // CloudAddCommand add command struct
@@ -27,322 +28,127 @@ func (ca *CloudAddCommand) Init() {
 		},
 		Example: cloudAddExample(),
 	}
+	ca.command.Flags().BoolP("cover", "c", false, "overwrite local kubeconfig files")
+	ca.command.Flags().StringSlice("context", []string{}, "specify the context to be added")
+	ca.command.Flags().StringSlice("context-template", []string{"filename"}, "define the attributes used for composing the context name, available values: filename, user, cluster, context, namespace")
+	ca.command.Flags().Bool("select-context", false, "select the context to be added in interactive mode")
+	ca.command.Flags().Bool("insecure-skip-tls-verify", false, "if true, the server's certificate will not be checked for validity")
+	ca.command.Flags().Bool("all", false, "add every cluster of the provider, in the region of --region_id for the regional ones")
 }
 
 func (ca *CloudAddCommand) runCloudAdd(cmd *cobra.Command, args []string) error {
 	provider, _ := ca.command.Flags().GetString("provider")
 	clusterID, _ := ca.command.Flags().GetString("cluster_id")
-	regionID, _ := ca.command.Flags().GetString("region_id")
 	cover, _ := ca.command.Flags().GetBool("cover")
 	context, _ := ca.command.Flags().GetStringSlice("context")
 	selectContext, _ := ca.command.Flags().GetBool("select-context")
 	contextTemplate, _ := ca.command.Flags().GetStringSlice("context-template")
 	insecureSkipTLSVerify, _ := ca.command.Flags().GetBool("insecure-skip-tls-verify")
-	var num int
-	if provider == "" {
-		num = selectCloud(Clouds, "Select Cloud")
-	} else {
-		num = checkFlags(provider)
+	all, _ := ca.command.Flags().GetBool("all")
+	if all && clusterID != "" {
+		return errors.New("--all can not be used with --cluster_id")
 	}
-	switch num {
-	case -1:
-		var allAlias []string
-		for _, cloud := range Clouds {
-			allAlias = append(allAlias, cloud.Alias...)
-		}
-		fmt.Printf("'%s' is not supported, supported cloud alias are %v \n", provider, allAlias)
-		return nil
-	case 0:
-		fmt.Println("⛅  Selected: AlibabaCloud")
-		accessKeyID, accessKeySecret := checkEnvForSecret(0)
-		ali := cloud.AliCloud{
-			AccessKeyID:     accessKeyID,
-			AccessKeySecret: accessKeySecret,
-		}
-		if clusterID == "" {
-			clusters, err := ali.ListCluster()
-			if err != nil {
-				return err
-			}
-			if len(clusters) == 0 {
-				return errors.New("no clusters found")
-			}
-			clusterNum := selectCluster(clusters, "Select Cluster")
-			kubeconfig, err := ali.GetKubeConfig(clusters[clusterNum].ID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, clusters[clusterNum].Name, "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		} else {
-			kubeconfig, err := ali.GetKubeConfig(clusterID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, fmt.Sprintf("alicloud-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		}
-	case 1:
-		fmt.Println("⛅  Selected: TencentCloud")
-		secretID, secretKey := checkEnvForSecret(1)
-		ten := cloud.TencentCloud{
-			SecretID:  secretID,
-			SecretKey: secretKey,
-		}
-		if regionID == "" {
-			regionList, err := ten.GetRegionID()
-			if err != nil {
-				return err
-			}
-			regionNum := selectRegion(regionList, "Select Region Name")
-			ten.RegionID = regionList[regionNum]
-		} else {
-			ten.RegionID = regionID
-		}
-
-		if clusterID == "" {
-			clusters, err := ten.ListCluster()
-			if err != nil {
-				return err
-			}
-			if len(clusters) == 0 {
-				return errors.New("no clusters found")
-			}
-			clusterNum := selectCluster(clusters, "Select Cluster")
-			kubeconfig, err := ten.GetKubeConfig(clusters[clusterNum].ID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, clusters[clusterNum].Name, "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		} else {
-			kubeconfig, err := ten.GetKubeConfig(clusterID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, fmt.Sprintf("tencent-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		}
-	case 2:
-		fmt.Println("⛅  Selected: Rancher")
-		serverURL, apiKey := checkEnvForSecret(2)
-		rancher := cloud.Rancher{
-			ServerURL: serverURL,
-			APIKey:    apiKey,
-		}
-		if clusterID == "" {
-			clusters, err := rancher.ListCluster()
-			if err != nil {
-				return err
-			}
-			if len(clusters) == 0 {
-				return errors.New("no clusters found")
-			}
-			clusterNum := selectCluster(clusters, "Select Cluster")
-			kubeconfig, err := rancher.GetKubeConfig(clusters[clusterNum].ID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, clusters[clusterNum].Name, "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		} else {
-			kubeconfig, err := rancher.GetKubeConfig(clusterID)
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load([]byte(kubeconfig))
-			if err != nil {
-				return err
-			}
-			err = AddToLocal(newConfig, fmt.Sprintf("rancher-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-			if err != nil {
-				return err
-			}
-		}
-	case 3:
-		fmt.Println("⛅  Selected: AWS")
-		accessKeyID, accessKeySecret := checkEnvForSecret(3)
-		aws := cloud.AWS{
-			AccessKeyID:     accessKeyID,
-			AccessKeySecret: accessKeySecret,
-		}
-		if regionID == "" {
-			regionList, err := cloud.GetRegionID()
-			if err != nil {
-				return err
-			}
-			regionNum := selectRegion(regionList, "Select Region ID")
-			aws.RegionID = regionList[regionNum]
-		} else {
-			aws.RegionID = regionID
-		}
-		if clusterID == "" {
-			clusters, err := aws.ListCluster()
-			if err != nil {
-				return err
-			}
-			if len(clusters) == 0 {
-				return errors.New("no clusters found")
-			}
-			clusterNum := selectCluster(clusters, "Select Cluster")
-			clusterID = clusters[clusterNum].ID
+	prompter := cloudPrompter(ca.command)
+	if prompter == nil {
+		if !cover && !dryRun {
+			return &cloud.MissingInputError{Flags: []string{"cover"}}
 		}
-		newConfig, err := aws.GetKubeConfigObj(clusterID)
-		if err != nil {
-			return err
+		if selectContext {
+			return errors.New("--select-context can not be used in non-interactive mode")
 		}
-		err = AddToLocal(newConfig, fmt.Sprintf("aws-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
+	}
+	cloudProvider, err := getProvider(provider, prompter)
+	var unsupported *unsupportedProviderError
+	if errors.As(err, &unsupported) && prompter != nil {
+		// an unsupported provider only prints the supported ones, the non-interactive mode fails
+		fmt.Println(err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	session := cloudSession(ca.command)
+	client, err := newCloudClient(cloudProvider, session)
+	if err != nil {
+		return err
+	}
+	var clusters []cloud.ClusterInfo
+	switch {
+	case clusterID != "":
+		cluster, err := clusterByID(cloudProvider, client, session, clusterID)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("%s: %s\n",
-			color.BlueString("Note"),
-			color.HiWhiteString(" please install the AWS CLI before normal use."))
-	case 4:
-		fmt.Println("⛅  Selected: Azure")
-		authModes := []string{"Default (SDK Auth)", "Service Principal"}
-		authMode := selectOption(nil, authModes, "Select Auth Type")
-		azure := cloud.Azure{
-			AuthMode:       cloud.AzureAuth(authMode),
-			TenantID:       os.Getenv("AZURE_TENANT_ID"),
-			SubscriptionID: os.Getenv("AZURE_SUBSCRIPTION_ID"),
+		clusters = []cloud.ClusterInfo{cluster}
+	case all || prompter == nil:
+		if !all {
+			return &cloud.MissingInputError{Flags: []string{"cluster_id", "all"}}
 		}
-
-		if azure.TenantID == "" {
-			azure.TenantID = PromptUI("Azure Tenant ID", "")
+		if clusters, err = client.ListCluster(); err != nil {
+			return err
 		}
-
-		if azure.AuthMode == cloud.AuthModeServicePrincipal {
-			azure.ClientID, azure.ClientSecret = checkEnvForSecret(4)
-			azure.ObjectID = os.Getenv("AZURE_OBJECT_ID")
-
-			if azure.ObjectID == "" {
-				azure.ObjectID = PromptUI("Azure Object ID", "")
-			}
+	default:
+		if clusters, err = client.ListCluster(); err != nil {
+			return err
 		}
-
-		if clusterID != "" {
-			clusterIDParts := strings.Split(clusterID, "/")
-			if len(clusterIDParts) != 9 {
-				return fmt.Errorf("invalid id %s", clusterID)
-			}
-			azure.SubscriptionID = clusterIDParts[2]
-			resourceGroup := clusterIDParts[4]
-			clusterName := clusterIDParts[8]
-
-			var (
-				kubeConfig []byte
-				err        error
-			)
-			kubeConfigType := selectOption(nil, []string{"User Config", "Admin Config"}, "Select Config Type")
-			switch kubeConfigType {
-			case 0:
-				kubeConfig, err = azure.GetKubeConfig(clusterName, resourceGroup)
-			case 1:
-				kubeConfig, err = azure.GetAdminKubeConfig(clusterName, resourceGroup)
-			default:
-				return fmt.Errorf("invalid config type %d", kubeConfigType)
-			}
-
-			if err != nil {
-				return err
-			}
-			newConfig, err := clientcmd.Load(kubeConfig)
-			if err != nil {
-				return err
-			}
-			return AddToLocal(newConfig, fmt.Sprintf("azure-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
+		if len(clusters) > 0 {
+			clusters = []cloud.ClusterInfo{clusters[selectCluster(clusters, "Select Cluster")]}
 		}
-
-		subscriptionList, err := azure.ListSubscriptions()
+	}
+	if len(clusters) == 0 {
+		return errors.New("no clusters found")
+	}
+	var failed []string
+	for _, cluster := range clusters {
+		err = addCloudCluster(client, cloudProvider, session, cluster, cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
 		if err != nil {
-			return err
-		}
-
-		var clusters []cloud.ClusterInfo
-
-		for _, subscription := range subscriptionList {
-			if azure.SubscriptionID != "" && azure.SubscriptionID != subscription.ID {
-				continue
-			}
-
-			subscriptionClusters, err := azure.ListCluster(subscription)
-			if err != nil {
+			if len(clusters) == 1 {
 				return err
 			}
-			clusters = append(clusters, subscriptionClusters...)
-		}
-
-		if len(clusters) == 0 {
-			return errors.New("no clusters found")
+			printWarning(os.Stderr, fmt.Sprintf("failed to add cluster 「%s」: %v\n", cluster.ID, err))
+			failed = append(failed, cluster.ID)
 		}
-
-		clusterNum := selectCluster(clusters, "Select Cluster")
-		cluster := clusters[clusterNum]
-
-		if azure.SubscriptionID == "" {
-			azure.SubscriptionID = strings.Split(cluster.ID, "/")[2]
-		}
-
-		resourceGroup := strings.Split(cluster.ID, "/")[4]
-
-		var kubeConfig []byte
-		kubeConfigType := selectOption(nil, []string{"User Config", "Admin Config"}, "Select Config Type")
-		switch kubeConfigType {
-		case 0:
-			kubeConfig, err = azure.GetKubeConfig(cluster.Name, resourceGroup)
-		case 1:
-			kubeConfig, err = azure.GetAdminKubeConfig(cluster.Name, resourceGroup)
-		default:
-			return fmt.Errorf("invalid config type %d", kubeConfigType)
-		}
-
-		if err != nil {
-			return err
-		}
-		newConfig, err := clientcmd.Load(kubeConfig)
-		if err != nil {
-			return err
-		}
-		return AddToLocal(newConfig, fmt.Sprintf("azure-%s", clusterID), "", cover, selectContext, contextTemplate, context, insecureSkipTLSVerify)
-
+	}
+	if cloudProvider.Note != "" {
+		fmt.Printf("%s: %s\n",
+			color.BlueString("Note"),
+			color.HiWhiteString(" "+cloudProvider.Note))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to add %d of %d clusters: %s", len(failed), len(clusters), strings.Join(failed, ", "))
 	}
 	return nil
 }
 
+// addCloudCluster fetch the kubeconfig of the cluster and add it to cfgFile
+func addCloudCluster(client cloud.Cluster, provider *cloud.Provider, session *cloud.Session, cluster cloud.ClusterInfo, cover, selectContext bool,
+	contextTemplate, context []string, insecureSkipTLSVerify bool) error {
+	newConfig, err := cloud.GetKubeConfigObj(client, cluster.ID)
+	if err != nil {
+		return err
+	}
+	name := provider.ClusterContextName(cluster)
+	if name == "" {
+		name = newConfig.CurrentContext
+	}
+	oldConfig, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	kco := &KubeConfigOption{
+		config:                newConfig,
+		fileName:              getFileName(name),
+		insecureSkipTLSVerify: insecureSkipTLSVerify,
+		nonInteractive:        session.Prompter == nil,
+		source:                cloudSource(provider, cluster, session),
+	}
+	return kco.addToLocal(oldConfig, name, "", cover, selectContext, contextTemplate, context)
+}
+
 func cloudAddExample() string {
 	return `
-# Supports AWS, Ali Cloud, Tencent Cloud and Rancher
+# Supports AWS, Azure, GCP, DigitalOcean, Ali Cloud, Tencent Cloud,
+# Huawei Cloud, Volcengine and Rancher
 # The AK/AS of the cloud platform will be retrieved directly 
 # if it exists in the environment variable, 
 # otherwise a prompt box will appear asking for it.
@@ -359,7 +165,9 @@ export TENCENTCLOUD_SECRET_KEY=YOUR_SECRET_KEY
 export RANCHER_SERVER_URL=https://xxx
 export RANCHER_API_KEY=YOUR_API_KEY
 
-# Set env AWS secret key
+# Set env AWS secret key, the profiles of the shared config
+# (including SSO) are used when unset or with --profile,
+# the clusters of all the enabled regions are listed unless a region is set
 # Note: Please install the AWS CLI before normal use.
 export AWS_ACCESS_KEY_ID=YOUR_AKID
 export AWS_SECRET_ACCESS_KEY=YOUR_SECRET_KEY
@@ -371,9 +179,33 @@ export AZURE_CLIENT_SECRET=YOUR_CLIENT_SECRET
 export AZURE_TENANT_ID=YOUR_TENANT_ID
 export AZURE_OBJECT_ID=YOUR_OBJECT_ID
 
+# Set env GCP credentials, a service account key or the ADC of
+# gcloud auth application-default login is used when unset,
+# the clusters of all the projects are listed unless a project is set
+# Note: Please install the gke-gcloud-auth-plugin before normal use.
+export GOOGLE_APPLICATION_CREDENTIALS=/path/to/key.json
+export GOOGLE_CLOUD_PROJECT=YOUR_PROJECT_ID
+
+# Set env DigitalOcean token
+export DIGITALOCEAN_TOKEN=YOUR_API_TOKEN
+
+# Set env Huawei Cloud secret key
+export HUAWEICLOUD_SDK_AK=YOUR_AKID
+export HUAWEICLOUD_SDK_SK=YOUR_SECRET_KEY
+
+# Set env Volcengine secret key
+export VOLCENGINE_ACCESS_KEY=YOUR_AKID
+export VOLCENGINE_SECRET_KEY=YOUR_SECRET_KEY
+
 # Interaction: select kubeconfig from the cloud
 kubecm cloud add
 # Add kubeconfig from cloud
 kubecm cloud add --provider alibabacloud --cluster_id=xxxxxx
+# Add every cluster of a region in CI, without any prompt
+kubecm cloud add --provider aws --region_id us-east-1 --all --cover --non-interactive
+# Add the EKS clusters of all the regions with an SSO profile, assuming a role of another account
+kubecm cloud add --provider aws --profile my-sso --role-arn arn:aws:iam::123456789012:role/admin --all --cover
+# Add a GKE cluster, the cluster id is PROJECT/LOCATION/NAME
+kubecm cloud add --provider gke --cluster_id=my-project/europe-west1/my-cluster
 `
 }
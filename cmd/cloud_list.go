@@ -3,6 +3,8 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/BussanQ/kubecm/pkg/cloud"
 	"github.com/bndr/gotabulate"
@@ -25,24 +27,42 @@ func (cl *CloudListCommand) Init() {
 		},
 		Example: cloudListExample(),
 	}
+	cl.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml, name")
 }
 
 func (cl *CloudListCommand) runCloudList(cmd *cobra.Command, args []string) error {
 	provider, _ := cl.command.Flags().GetString("provider")
-	regionID, _ := cl.command.Flags().GetString("region_id")
-	var num int
-	if provider == "" {
-		num = selectCloud(Clouds, "Select Cloud")
-	} else {
-		num = checkFlags(provider)
+	output, _ := cl.command.Flags().GetString("output")
+	// the table shows every field of the clusters, there is no wide output
+	if output != "" && output != OutputJSON && output != OutputYAML && output != OutputName {
+		return fmt.Errorf("unsupported output format %q, the available values are: json, yaml, name", output)
+	}
+	prompter := cloudPrompter(cl.command)
+	cloudProvider, err := getProvider(provider, prompter)
+	if err != nil {
+		return err
 	}
-	clusters, err := getClusters(provider, regionID, num)
+	clusters, err := getClusters(cloudProvider, cloudSession(cl.command))
 	if err != nil {
 		return err
 	}
 	if len(clusters) == 0 {
 		return errors.New("no clusters found")
 	}
+	return printClusterList(os.Stdout, clusters, output)
+}
+
+// printClusterList print the clusters in the output format of the -o flag
+func printClusterList(out io.Writer, clusters []cloud.ClusterInfo, output string) error {
+	switch output {
+	case OutputJSON, OutputYAML:
+		return printStructured(out, output, clusters)
+	case OutputName:
+		for _, k := range clusters {
+			fmt.Fprintln(out, k.ID)
+		}
+		return nil
+	}
 	return printListTable(clusters)
 }
 
@@ -84,9 +104,27 @@ export TENCENTCLOUD_SECRET_KEY=xxx
 # Set env Rancher secret key
 export RANCHER_SERVER_URL=https://xxx
 export RANCHER_API_KEY=xxx
+# Set env GCP credentials, the ADC is used when unset
+export GOOGLE_APPLICATION_CREDENTIALS=/path/to/key.json
+# Set env DigitalOcean token
+export DIGITALOCEAN_TOKEN=xxx
+# Set env Huawei Cloud secret key
+export HUAWEICLOUD_SDK_AK=xxx
+export HUAWEICLOUD_SDK_SK=xxx
+# Set env Volcengine secret key
+export VOLCENGINE_ACCESS_KEY=xxx
+export VOLCENGINE_SECRET_KEY=xxx
 # Interaction: list kubeconfig from cloud
 kubecm cloud list
 # Add kubeconfig from cloud
 kubecm cloud list --provider alibabacloud --cluster_id=xxxxxx
+# List the DOKS clusters of one region
+kubecm cloud list --provider doks --region_id ams3
+# List the CCE clusters of one region
+kubecm cloud list --provider cce --region_id cn-north-4
+# List the GKE clusters of one location
+kubecm cloud list --provider gke --region_id europe-west1
+# Output the clusters as json
+kubecm cloud list --provider alibabacloud -o json
 `
 }
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/BussanQ/kubecm/pkg/cloud"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 func Test_printListTable(t *testing.T) {
@@ -36,3 +40,37 @@ func Test_printListTable(t *testing.T) {
 		})
 	}
 }
+
+func Test_printClusterList(t *testing.T) {
+	clusters := []cloud.ClusterInfo{
+		{ID: "c-1", Name: "one", RegionID: "cn-shanghai", K8sVersion: "v1.30.0"},
+		{ID: "c-2", Name: "two", RegionID: "cn-beijing", K8sVersion: "v1.31.0"},
+	}
+	out := new(bytes.Buffer)
+	if err := printClusterList(out, clusters, OutputName); err != nil {
+		t.Fatalf("printClusterList() error = %v", err)
+	}
+	if out.String() != "c-1\nc-2\n" {
+		t.Errorf("printClusterList() got = %q", out.String())
+	}
+	out.Reset()
+	if err := printClusterList(out, clusters, OutputJSON); err != nil {
+		t.Fatalf("printClusterList() error = %v", err)
+	}
+	var got []cloud.ClusterInfo
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil || len(got) != 2 || got[1].RegionID != "cn-beijing" {
+		t.Errorf("printClusterList() got = %s, error = %v", out.String(), err)
+	}
+}
+
+func Test_runCloudList_output(t *testing.T) {
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCloudCommand(t, "list", "--non-interactive", "--provider", "test", "-o", "wide"); err == nil {
+		t.Errorf("runCloudList() -o wide should fail, the table has no wide columns")
+	}
+}
@@ -0,0 +1,485 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/BussanQ/kubecm/pkg/cloud"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// cloudSyncWorkers is the number of kubeconfigs fetched at the same time
+const cloudSyncWorkers = 8
+
+// CloudSyncCommand sync command struct
+type CloudSyncCommand struct {
+	CloudCommand
+}
+
+// Init CloudSyncCommand
+func (cs *CloudSyncCommand) Init() {
+	cs.command = &cobra.Command{
+		Use:   "sync",
+		Short: "Sync the kubeconfig of all the clusters of a cloud",
+		Long:  "Add the clusters of a cloud that are missing from the kubeconfig, update the endpoint and CA of the ones that changed, and delete the ones that no longer exist. Without --provider the providers and regions the contexts were added from are synced.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cs.runCloudSync(cmd, args)
+		},
+		Example: cloudSyncExample(),
+	}
+	cs.command.Flags().String("name-template", "", "go template of the names of the added contexts, with the fields .Provider, .Prefix, .Name, .ID, .RegionID and .Account, the name of kubecm cloud add by default")
+	cs.command.Flags().Bool("prune", false, "delete the contexts of the clusters that no longer exist without asking, except the protected ones")
+}
+
+// cloudKubeConfig the kubeconfig fetched for a cluster
+type cloudKubeConfig struct {
+	cluster cloud.ClusterInfo
+	config  *clientcmdapi.Config
+	err     error
+}
+
+// cloudContextName the fields of the --name-template of kubecm cloud sync
+type cloudContextName struct {
+	Provider string
+	Prefix   string
+	Name     string
+	ID       string
+	RegionID string
+	Account  string
+}
+
+func (cs *CloudSyncCommand) runCloudSync(cmd *cobra.Command, args []string) error {
+	provider, _ := cs.command.Flags().GetString("provider")
+	regionID, _ := cs.command.Flags().GetString("region_id")
+	nameTemplate, _ := cs.command.Flags().GetString("name-template")
+	prune, _ := cs.command.Flags().GetBool("prune")
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid --name-template: %v", err)
+	}
+	prompter := cloudPrompter(cs.command)
+	if provider == "" {
+		origins, err := recordedCloudOrigins()
+		if err != nil {
+			return err
+		}
+		if len(origins) > 0 {
+			var failed []string
+			for _, origin := range origins {
+				if regionID != "" && origin.regionID != "" && origin.regionID != regionID {
+					continue
+				}
+				fmt.Printf("⛅  Syncing: %s %s\n", origin.provider.Name, strings.TrimSpace(origin.regionID+" "+origin.profile+" "+origin.roleARN))
+				session := &cloud.Session{
+					RegionID:   origin.regionID,
+					Prompter:   prompter,
+					Profile:    origin.profile,
+					RoleARN:    origin.roleARN,
+					ExternalID: origin.externalID,
+				}
+				if err = syncCloud(origin.provider, session, tmpl, prune); err != nil {
+					printWarning(os.Stderr, fmt.Sprintf("failed to sync %s %s: %v\n", origin.provider.Name, origin.regionID, err))
+					failed = append(failed, strings.TrimSpace(origin.provider.Alias[0]+" "+origin.regionID))
+				}
+			}
+			if len(failed) > 0 {
+				return fmt.Errorf("failed to sync %s", strings.Join(failed, ", "))
+			}
+			return nil
+		}
+	}
+	cloudProvider, err := getProvider(provider, prompter)
+	if err != nil {
+		return err
+	}
+	return syncCloud(cloudProvider, cloudSession(cs.command), tmpl, prune)
+}
+
+// cloudOrigin a provider, region and identity the contexts of cfgFile were added from
+type cloudOrigin struct {
+	provider   *cloud.Provider
+	regionID   string
+	profile    string
+	roleARN    string
+	externalID string
+}
+
+// recordedCloudOrigins return the providers and regions recorded as the source of the contexts of cfgFile,
+// the region is only kept for the regional providers
+func recordedCloudOrigins() ([]cloudOrigin, error) {
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[cloudOrigin]bool)
+	var origins []cloudOrigin
+	for name, meta := range metadata {
+		if _, ok := config.Contexts[name]; !ok || meta.Source == nil || meta.Source.Provider == "" {
+			continue
+		}
+		provider := cloud.LookupProvider(meta.Source.Provider)
+		if provider == nil {
+			continue
+		}
+		origin := cloudOrigin{
+			provider:   provider,
+			profile:    meta.Source.Profile,
+			roleARN:    meta.Source.RoleARN,
+			externalID: meta.Source.ExternalID,
+		}
+		if provider.Regional {
+			if meta.Source.RegionID == "" {
+				continue
+			}
+			origin.regionID = meta.Source.RegionID
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		if origins[i].provider.Alias[0] != origins[j].provider.Alias[0] {
+			return origins[i].provider.Alias[0] < origins[j].provider.Alias[0]
+		}
+		return fmt.Sprint(origins[i].regionID, origins[i].profile, origins[i].roleARN) <
+			fmt.Sprint(origins[j].regionID, origins[j].profile, origins[j].roleARN)
+	})
+	return origins, nil
+}
+
+// syncCloud sync the clusters of the provider in the region of the session, all the regions of a provider
+// that is not regional when it is empty
+func syncCloud(cloudProvider *cloud.Provider, session *cloud.Session, tmpl *template.Template, prune bool) error {
+	prompter := session.Prompter
+	client, err := newCloudClient(cloudProvider, session)
+	if err != nil {
+		return err
+	}
+	listed, skipped, err := cloud.ListClusters(client)
+	if err != nil {
+		return err
+	}
+	var clusters []cloud.ClusterInfo
+	for _, cluster := range listed {
+		// the region of the providers listing all the regions is a filter
+		if session.RegionID == "" || cluster.RegionID == "" || cluster.RegionID == session.RegionID {
+			clusters = append(clusters, cluster)
+		}
+	}
+	results := fetchKubeConfigs(client, clusters)
+	var failed []string
+	for _, result := range results {
+		if result.err != nil {
+			printWarning(os.Stderr, fmt.Sprintf("failed to get the kubeconfig of cluster 「%s」: %v\n", result.cluster.ID, result.err))
+			failed = append(failed, result.cluster.ID)
+		}
+	}
+
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		return err
+	}
+	managed := managedContexts(config, metadata, cloudProvider, session)
+
+	plan := config.DeepCopy()
+	sources, changed, err := syncCloudContexts(os.Stdout, plan, managed, cloudProvider, session, results, tmpl)
+	if err != nil {
+		return err
+	}
+	// the contexts adopted by this sync are kept, even when recorded under another cluster id
+	var stale []string
+	for _, name := range staleContexts(managed, listed) {
+		if sources[name] == nil {
+			stale = append(stale, name)
+		}
+	}
+	// the clusters of the skipped regions or projects may still exist
+	if len(skipped) > 0 && len(stale) > 0 {
+		printWarning(os.Stderr, fmt.Sprintf("could not list the clusters of %s, the contexts of the clusters not listed are kept\n",
+			strings.Join(skipped, ", ")))
+		stale = nil
+	}
+	pruned := selectPrunedContexts(stale, prune, prompter)
+	if changed == 0 && len(pruned) == 0 {
+		fmt.Println("Everything is up to date.")
+	} else {
+		err = ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+			// the changes were printed while planning
+			if _, _, err := syncCloudContexts(io.Discard, config, managed, cloudProvider, session, results, tmpl); err != nil {
+				return err
+			}
+			if len(pruned) > 0 {
+				return deleteContext(pruned, config)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !dryRun {
+		err = updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+			for name, source := range sources {
+				setContextSource(metadata, name, source)
+			}
+			for _, name := range pruned {
+				delete(metadata, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to sync %d of %d clusters: %s", len(failed), len(clusters), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// fetchKubeConfigs get the kubeconfig of the clusters concurrently, in the order of the clusters.
+// The first one is fetched alone, so that a client asking for input on its first fetch, like the
+// config type of azure, asks once before the others start
+func fetchKubeConfigs(client cloud.Cluster, clusters []cloud.ClusterInfo) []cloudKubeConfig {
+	results := make([]cloudKubeConfig, len(clusters))
+	if len(clusters) == 0 {
+		return results
+	}
+	config, err := cloud.GetKubeConfigObj(client, clusters[0].ID)
+	results[0] = cloudKubeConfig{cluster: clusters[0], config: config, err: err}
+	workers := make(chan struct{}, cloudSyncWorkers)
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		if i == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			config, err := cloud.GetKubeConfigObj(client, cluster.ID)
+			results[i] = cloudKubeConfig{cluster: cluster, config: config, err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// managedContexts return the contexts of config that were added from the clusters of the provider with
+// the profile and role of the session, only the ones of its region when set, keyed by the cluster id
+// ListCluster returns
+func managedContexts(config *clientcmdapi.Config, metadata map[string]*ContextMetadata, provider *cloud.Provider, session *cloud.Session) map[string]string {
+	managed := make(map[string]string)
+	for name, meta := range metadata {
+		source := meta.Source
+		if source == nil || source.Provider != provider.Alias[0] || source.ClusterID == "" {
+			continue
+		}
+		if source.Profile != session.Profile || source.RoleARN != session.RoleARN {
+			continue
+		}
+		if session.RegionID != "" && source.RegionID != "" && source.RegionID != session.RegionID {
+			continue
+		}
+		if _, ok := config.Contexts[name]; !ok {
+			continue
+		}
+		// the id is recorded as given to cloud add --cluster_id
+		id := source.ClusterID
+		if cluster, err := provider.ClusterByID(source.ClusterID, source.RegionID); err == nil {
+			id = cluster.ID
+		}
+		managed[id] = name
+	}
+	return managed
+}
+
+// staleContexts return the sorted managed contexts whose cluster is not listed anymore
+func staleContexts(managed map[string]string, clusters []cloud.ClusterInfo) []string {
+	listed := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		listed[cluster.ID] = true
+	}
+	var stale []string
+	for id, name := range managed {
+		if !listed[id] {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// selectPrunedContexts return the stale contexts to delete, all of them with --prune, the confirmed ones
+// when interactive, none otherwise. The protected contexts are only deleted when confirmed.
+func selectPrunedContexts(stale []string, prune bool, prompter cloud.Prompter) []string {
+	var pruned []string
+	for _, name := range stale {
+		if prune && !isProtected(name) {
+			pruned = append(pruned, name)
+			continue
+		}
+		if prompter == nil {
+			if prune {
+				printYellow(os.Stdout, fmt.Sprintf("The cluster of 「%s」 no longer exists, it is protected and not pruned\n", name))
+			} else {
+				printYellow(os.Stdout, fmt.Sprintf("The cluster of 「%s」 no longer exists, run with --prune to delete it\n", name))
+			}
+			continue
+		}
+		if BoolUI(fmt.Sprintf("The cluster of 「%s」 no longer exists, do you want to delete the context?", name)) != "True" {
+			continue
+		}
+		if err := confirmProtected(name, false); err != nil {
+			printYellow(os.Stdout, fmt.Sprintf("%v\n", err))
+			continue
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned
+}
+
+// syncCloudContexts add the fetched kubeconfigs missing from config, and update the endpoint and CA of
+// the ones already in it. It return the source of the contexts kept in sync and the number of changes.
+func syncCloudContexts(out io.Writer, config *clientcmdapi.Config, managed map[string]string, provider *cloud.Provider,
+	session *cloud.Session, results []cloudKubeConfig, tmpl *template.Template) (map[string]*ContextSource, int, error) {
+	sources := make(map[string]*ContextSource)
+	changed := 0
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		newCtxName := result.config.CurrentContext
+		if newCtxName == "" && len(result.config.Contexts) == 1 {
+			for name := range result.config.Contexts {
+				newCtxName = name
+			}
+		}
+		newCtx := result.config.Contexts[newCtxName]
+		if newCtx == nil || result.config.Clusters[newCtx.Cluster] == nil {
+			printWarning(out, fmt.Sprintf("the kubeconfig of cluster 「%s」 has no current context, skipped\n", result.cluster.ID))
+			continue
+		}
+		newCluster := result.config.Clusters[newCtx.Cluster]
+
+		name, ok := managed[result.cluster.ID]
+		if !ok {
+			var err error
+			if name, err = syncContextName(tmpl, provider, result.cluster, newCtxName); err != nil {
+				return nil, 0, err
+			}
+		}
+		if ctx, exists := config.Contexts[name]; exists {
+			cluster := config.Clusters[ctx.Cluster]
+			// a context of the same name and endpoint, added before its source was recorded, is adopted
+			if !ok && (cluster == nil || cluster.Server != newCluster.Server) {
+				printWarning(out, fmt.Sprintf("「%s」 already exists and is not cluster 「%s」, skipped\n", name, result.cluster.ID))
+				continue
+			}
+			if !ok {
+				sources[name] = cloudSource(provider, result.cluster, session)
+			}
+			if cluster == nil {
+				cluster = clientcmdapi.NewCluster()
+				config.Clusters[ctx.Cluster] = cluster
+			}
+			if cluster.Server == newCluster.Server && bytes.Equal(cluster.CertificateAuthorityData, newCluster.CertificateAuthorityData) {
+				continue
+			}
+			cluster.Server = newCluster.Server
+			cluster.CertificateAuthorityData = newCluster.CertificateAuthorityData
+			cluster.CertificateAuthority = newCluster.CertificateAuthority
+			changed++
+			fmt.Fprintf(out, "Update Context: %s \n", name)
+			continue
+		}
+
+		clusterName := uniqueName(config.Clusters, name)
+		userName := uniqueName(config.AuthInfos, name)
+		config.Clusters[clusterName] = newCluster.DeepCopy()
+		if user := result.config.AuthInfos[newCtx.AuthInfo]; user != nil {
+			config.AuthInfos[userName] = user.DeepCopy()
+		} else {
+			config.AuthInfos[userName] = clientcmdapi.NewAuthInfo()
+		}
+		ctx := newCtx.DeepCopy()
+		ctx.Cluster = clusterName
+		ctx.AuthInfo = userName
+		config.Contexts[name] = ctx
+		sources[name] = cloudSource(provider, result.cluster, session)
+		changed++
+		fmt.Fprintf(out, "Add Context: %s \n", name)
+	}
+	return sources, changed, nil
+}
+
+// syncContextName return the name of the context of the cluster from the --name-template, the name
+// kubecm cloud add uses when it is empty
+func syncContextName(tmpl *template.Template, provider *cloud.Provider, cluster cloud.ClusterInfo, currentContext string) (string, error) {
+	if tmpl.Root == nil || len(tmpl.Root.Nodes) == 0 {
+		if name := provider.ClusterContextName(cluster); name != "" {
+			return name, nil
+		}
+		return currentContext, nil
+	}
+	var name bytes.Buffer
+	err := tmpl.Execute(&name, cloudContextName{
+		Provider: provider.Alias[0],
+		Prefix:   provider.ContextPrefix,
+		Name:     cluster.Name,
+		ID:       cluster.ID,
+		RegionID: cluster.RegionID,
+		Account:  cluster.Account,
+	})
+	if err != nil {
+		return "", fmt.Errorf("invalid --name-template: %v", err)
+	}
+	if strings.TrimSpace(name.String()) == "" {
+		return "", errors.New("--name-template gives an empty context name")
+	}
+	return name.String(), nil
+}
+
+// uniqueName return name, or name-N when it is already a key of entries
+func uniqueName[V any](entries map[string]V, name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := entries[unique]; !ok {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+func cloudSyncExample() string {
+	return `
+# The credentials are read from the same env vars as kubecm cloud add
+# Sync the providers and regions the contexts were added from by kubecm cloud add or sync
+kubecm cloud sync
+# Sync the EKS clusters of a region, asking before deleting the contexts of deleted clusters
+kubecm cloud sync --provider aws --region_id us-east-1
+# Sync in CI, deleting the contexts of deleted clusters
+kubecm cloud sync --provider aws --region_id us-east-1 --prune --non-interactive
+# Name the added contexts after the provider, region and cluster
+kubecm cloud sync --provider alibabacloud --region_id cn-hangzhou --name-template '{{.Prefix}}-{{.RegionID}}-{{.Name}}'
+`
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/cloud"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_runCloudSync(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv("TEST_CLOUD_KEY", "key")
+	provider := registerTestProvider(t, "one", "two")
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r")
+	if err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if ctx, ok := config.Contexts[name]; !ok || config.Clusters[ctx.Cluster].Server != "https://"+name+".example.org" {
+			t.Errorf("runCloudSync() did not add context %s: %v", name, config.Contexts)
+		}
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ContextSource{Provider: "test", ClusterID: "two", RegionID: "r"}
+	if metadata["two"] == nil || metadata["two"].Source.ImportedAt.IsZero() {
+		t.Fatalf("runCloudSync() recorded %+v without import time", metadata["two"])
+	}
+	metadata["two"].Source.ImportedAt = time.Time{}
+	if metadata["two"] == nil || !reflect.DeepEqual(metadata["two"].Source, want) {
+		t.Errorf("runCloudSync() recorded %+v, want %+v", metadata["two"], want)
+	}
+
+	origins, err := recordedCloudOrigins()
+	if err != nil || len(origins) != 1 || origins[0].provider != provider || origins[0].regionID != "r" {
+		t.Errorf("recordedCloudOrigins() got %+v, error = %v", origins, err)
+	}
+
+	// the endpoint of one moved back, two was deleted from the cloud
+	err = UpdateConfigFile(cfgFile, func(config *clientcmdapi.Config) error {
+		config.Clusters[config.Contexts["one"].Cluster].Server = "https://old.example.org"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.New = func(s *cloud.Session) (cloud.Cluster, error) {
+		return &listCluster{testCluster: testCluster{regionID: s.RegionID}, clusters: []string{"one"}}, nil
+	}
+	config, err = runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r")
+	if err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+	if got := config.Clusters[config.Contexts["one"].Cluster].Server; got != "https://one.example.org" {
+		t.Errorf("runCloudSync() did not update the endpoint of one, got %s", got)
+	}
+	if _, ok := config.Contexts["two"]; !ok {
+		t.Errorf("runCloudSync() pruned two without --prune")
+	}
+	// the clusters of another region are not pruned
+	if config, err = runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "other", "--prune"); err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+	if _, ok := config.Contexts["two"]; !ok {
+		t.Errorf("runCloudSync() of another region pruned two")
+	}
+
+	// nor the ones added with another profile
+	if config, err = runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r", "--profile", "other", "--prune"); err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+	if _, ok := config.Contexts["two"]; !ok {
+		t.Errorf("runCloudSync() with another profile pruned two")
+	}
+
+	if config, err = runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r", "--prune"); err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+	if _, ok := config.Contexts["two"]; ok || len(config.Contexts) != len(appendMergeConfig.Contexts)+1 {
+		t.Errorf("runCloudSync() --prune got contexts %v", config.Contexts)
+	}
+	if metadata, _ = loadMetadata(cfgFile); metadata["two"] != nil {
+		t.Errorf("runCloudSync() --prune kept the metadata of two")
+	}
+
+	// without --provider the recorded providers and regions are synced
+	if _, err = runCloudCommand(t, "sync", "--non-interactive"); err != nil {
+		t.Errorf("runCloudSync() of the recorded origins error = %v", err)
+	}
+}
+
+func Test_runCloudSync_clusterID(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv("TEST_CLOUD_KEY", "key")
+	provider := registerTestProvider(t)
+	provider.Regional = false
+	provider.ParseClusterID = parseRegionClusterID
+	provider.New = func(s *cloud.Session) (cloud.Cluster, error) {
+		return &regionCluster{listCluster{testCluster: testCluster{regionID: "r"}, clusters: []string{"one"}}}, nil
+	}
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := runCloudCommand(t, "add", "--non-interactive", "--cover", "--provider", "test", "--cluster_id", "one", "--region_id", "r")
+	if err != nil {
+		t.Fatalf("runCloudAdd() error = %v", err)
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := metadata["one"].Source; source.ClusterID != "r/one" || source.RegionID != "r" {
+		t.Errorf("runCloudAdd() recorded %+v, want the cluster id r/one", source)
+	}
+
+	tests := []struct {
+		name      string
+		clusterID string
+	}{
+		{"listed-id", "r/one"},
+		// the id recorded before it was parsed
+		{"name", "one"},
+		// a context adopted by the sync is not pruned by its old id
+		{"adopted", "gone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+				if metadata["one"] == nil {
+					return errors.New("no metadata of one")
+				}
+				metadata["one"].Source.ClusterID = tt.clusterID
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--prune")
+			if err != nil {
+				t.Fatalf("runCloudSync() error = %v", err)
+			}
+			if _, ok := config.Contexts["one"]; !ok {
+				t.Errorf("runCloudSync() --prune deleted the context of the listed cluster")
+			}
+			if metadata, _ := loadMetadata(cfgFile); metadata["one"] == nil || metadata["one"].Source == nil {
+				t.Errorf("runCloudSync() --prune deleted the metadata of the listed cluster")
+			}
+		})
+	}
+}
+
+// partialCluster a listCluster whose listing skipped some regions
+type partialCluster struct {
+	listCluster
+	skipped []string
+}
+
+func (c *partialCluster) ListClusterPartial() ([]cloud.ClusterInfo, []string, error) {
+	clusters, err := c.ListCluster()
+	return clusters, c.skipped, err
+}
+
+func Test_runCloudSync_prune(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv("TEST_CLOUD_KEY", "key")
+	t.Setenv(protectedEnv, "")
+	provider := registerTestProvider(t, "one", "two")
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r"); err != nil {
+		t.Fatalf("runCloudSync() error = %v", err)
+	}
+
+	// two is not listed anymore
+	var skipped []string
+	provider.New = func(s *cloud.Session) (cloud.Cluster, error) {
+		return &partialCluster{listCluster{testCluster: testCluster{regionID: s.RegionID}, clusters: []string{"one"}}, skipped}, nil
+	}
+	tests := []struct {
+		name      string
+		skipped   []string
+		protected string
+		wantTwo   bool
+	}{
+		{"partial-listing", []string{"r2"}, "", true},
+		{"protected", nil, "t*o", true},
+		{"pruned", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipped = tt.skipped
+			t.Setenv(protectedEnv, tt.protected)
+			config, err := runCloudCommand(t, "sync", "--non-interactive", "--provider", "test", "--region_id", "r", "--prune")
+			if err != nil {
+				t.Fatalf("runCloudSync() error = %v", err)
+			}
+			if _, ok := config.Contexts["two"]; ok != tt.wantTwo {
+				t.Errorf("runCloudSync() --prune kept two = %v, want %v", ok, tt.wantTwo)
+			}
+		})
+	}
+}
+
+func Test_syncCloudContexts(t *testing.T) {
+	provider := &cloud.Provider{Alias: []string{"test"}, ContextPrefix: "test"}
+	client := &testCluster{}
+	results := fetchKubeConfigs(client, []cloud.ClusterInfo{{ID: "one", Name: "one"}, {ID: "broken"}, {ID: "root", Name: "root-context"}})
+	if results[0].err != nil || results[1].err == nil || results[2].cluster.ID != "root" {
+		t.Fatalf("fetchKubeConfigs() got %+v", results)
+	}
+	config := appendMergeConfig.DeepCopy()
+	tmpl := template.Must(template.New("name").Parse(""))
+	sources, changed, err := syncCloudContexts(io.Discard, config, map[string]string{}, provider, &cloud.Session{}, results, tmpl)
+	if err != nil {
+		t.Fatalf("syncCloudContexts() error = %v", err)
+	}
+	// root-context is another cluster, it is neither changed nor recorded
+	if changed != 1 || len(sources) != 1 || sources["one"] == nil {
+		t.Errorf("syncCloudContexts() got sources %v, changed %d", sources, changed)
+	}
+	if config.Clusters["pig-cluster"].Server != "http://pig.org:8080" {
+		t.Errorf("syncCloudContexts() changed root-context")
+	}
+	// the cluster of the same name is kept
+	config.Clusters["one"] = config.Clusters["one"].DeepCopy()
+	delete(config.Contexts, "one")
+	if _, _, err = syncCloudContexts(io.Discard, config, map[string]string{}, provider, &cloud.Session{}, results[:1], tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if ctx := config.Contexts["one"]; ctx == nil || ctx.Cluster != "one-2" || ctx.AuthInfo != "one-2" {
+		t.Errorf("syncCloudContexts() got context %+v", ctx)
+	}
+}
+
+func Test_syncContextName(t *testing.T) {
+	provider := &cloud.Provider{Alias: []string{"aws", "eks"}, ContextPrefix: "aws"}
+	cluster := cloud.ClusterInfo{ID: "id", Name: "web", RegionID: "us-east-1", Account: "prod"}
+	tests := []struct {
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{template: "", want: "web"},
+		{template: "{{.Provider}}-{{.RegionID}}-{{.Name}}", want: "aws-us-east-1-web"},
+		{template: "{{.Prefix}}-{{.Account}}-{{.ID}}", want: "aws-prod-id"},
+		{template: "{{.Missing}}", wantErr: true},
+		{template: " ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl := template.Must(template.New("name").Option("missingkey=error").Parse(tt.template))
+			got, err := syncContextName(tmpl, provider, cluster, "current")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncContextName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("syncContextName() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	gke := &cloud.Provider{Alias: []string{"gcp"}, ContextName: func(cloud.ClusterInfo) string { return "" }}
+	if got, _ := syncContextName(template.Must(template.New("name").Parse("")), gke, cluster, "gke_p_l_web"); got != "gke_p_l_web" {
+		t.Errorf("syncContextName() got = %v, want the current context", got)
+	}
+}
+
+// firstFetchCluster a testCluster recording the fetches running while the first one did
+type firstFetchCluster struct {
+	testCluster
+	mu         sync.Mutex
+	fetches    int
+	running    int
+	concurrent bool
+}
+
+func (c *firstFetchCluster) GetKubeConfig(clusterID string) (string, error) {
+	c.mu.Lock()
+	c.fetches++
+	first := c.fetches == 1
+	c.running++
+	c.mu.Unlock()
+	if first {
+		time.Sleep(20 * time.Millisecond)
+	}
+	c.mu.Lock()
+	if first && c.running > 1 {
+		c.concurrent = true
+	}
+	c.running--
+	c.mu.Unlock()
+	return c.testCluster.GetKubeConfig(clusterID)
+}
+
+func Test_fetchKubeConfigs_first(t *testing.T) {
+	client := &firstFetchCluster{}
+	var clusters []cloud.ClusterInfo
+	for i := 0; i < 10; i++ {
+		clusters = append(clusters, cloud.ClusterInfo{ID: fmt.Sprintf("c-%d", i)})
+	}
+	results := fetchKubeConfigs(client, clusters)
+	if client.concurrent {
+		t.Errorf("fetchKubeConfigs() fetched other clusters during the first fetch")
+	}
+	for i, result := range results {
+		if result.err != nil || result.cluster.ID != clusters[i].ID {
+			t.Errorf("fetchKubeConfigs() got %+v for %s", result, clusters[i].ID)
+		}
+	}
+}
@@ -1,96 +1,333 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/cloud"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-func Test_checkFlags(t *testing.T) {
-	type args struct {
-		provider string
-	}
+func Test_getProvider(t *testing.T) {
 	tests := []struct {
-		name string
-		args args
-		want int
+		alias   string
+		want    string
+		wantErr bool
 	}{
-		// TODO: Add test cases.
-		{
-			name: "exist",
-			args: args{
-				provider: "alibabacloud",
-			},
-			want: 0,
-		},
-		{
-			name: "notExist",
-			args: args{
-				provider: "alibabaclou",
-			},
-			want: -1,
-		},
+		{alias: "alibabacloud", want: "AlibabaCloud"},
+		{alias: "eks", want: "AWS"},
+		{alias: "gke", want: "GCP"},
+		{alias: "do", want: "DigitalOcean"},
+		{alias: "huawei", want: "HuaweiCloud"},
+		{alias: "vke", want: "Volcengine"},
+		{alias: "alibabaclou", wantErr: true},
 	}
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := checkFlags(tt.args.provider); got != tt.want {
-				t.Errorf("checkFlags() = %v, want %v", got, tt.want)
+		t.Run(tt.alias, func(t *testing.T) {
+			got, err := getProvider(tt.alias, promptPrompter{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Name != tt.want {
+				t.Errorf("getProvider() = %v, want %v", got.Name, tt.want)
 			}
 		})
 	}
 }
 
-func Test_checkEnvForSecret(t *testing.T) {
-	type args struct {
-		num int
+// testPrompter answers the inputs and selections of the cloud providers
+type testPrompter struct {
+	inputs  map[string]string
+	selects map[string]int
+	asked   []string
+}
+
+func (p *testPrompter) Input(label string) (string, error) {
+	p.asked = append(p.asked, label)
+	return p.inputs[label], nil
+}
+
+func (p *testPrompter) Select(label string, options []string) (int, error) {
+	p.asked = append(p.asked, label)
+	i, ok := p.selects[label]
+	if !ok || i >= len(options) {
+		return 0, errors.New("unexpected selection " + label)
+	}
+	return i, nil
+}
+
+// testCluster a cloud.Cluster of a fake provider, in the region it was created for
+type testCluster struct {
+	regionID string
+}
+
+func (c *testCluster) GetRegionID() ([]string, error) {
+	return []string{"region-a", "region-b"}, nil
+}
+
+func (c *testCluster) ListCluster() ([]cloud.ClusterInfo, error) {
+	return []cloud.ClusterInfo{{ID: "c-1", Name: "one", RegionID: c.regionID}}, nil
+}
+
+func (c *testCluster) GetKubeConfig(clusterID string) (string, error) {
+	if clusterID == "broken" {
+		return "", errors.New("cluster broken is not available")
+	}
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://%[1]s.example.org
+  name: %[1]s
+contexts:
+- context:
+    cluster: %[1]s
+    user: %[1]s
+  name: %[1]s
+current-context: %[1]s
+users:
+- name: %[1]s
+  user:
+    token: fake
+`, clusterID), nil
+}
+
+// registerTestProvider register a regional fake provider reading TEST_CLOUD_KEY, listing clusters
+func registerTestProvider(t *testing.T, clusters ...string) *cloud.Provider {
+	provider := &cloud.Provider{
+		Name:          "Test",
+		Alias:         []string{"test"},
+		Credentials:   []cloud.Credential{{Env: "TEST_CLOUD_KEY", Prompt: "Test Key"}},
+		Regional:      true,
+		ContextPrefix: "test",
+		New: func(s *cloud.Session) (cloud.Cluster, error) {
+			return &listCluster{testCluster: testCluster{regionID: s.RegionID}, clusters: clusters}, nil
+		},
+	}
+	providers := cloud.Providers
+	cloud.Providers = append(cloud.Providers[:len(providers):len(providers)], provider)
+	t.Cleanup(func() { cloud.Providers = providers })
+	return provider
+}
+
+// listCluster a testCluster listing the clusters
+type listCluster struct {
+	testCluster
+	clusters []string
+}
+
+func (c *listCluster) ListCluster() ([]cloud.ClusterInfo, error) {
+	var clusters []cloud.ClusterInfo
+	for _, id := range c.clusters {
+		clusters = append(clusters, cloud.ClusterInfo{ID: id, Name: id, RegionID: c.regionID})
+	}
+	return clusters, nil
+}
+
+// parseRegionClusterID parse the cluster ids REGION/NAME of regionCluster, as the ones of EKS
+func parseRegionClusterID(clusterID, regionID string) (cloud.ClusterInfo, error) {
+	if i := strings.LastIndex(clusterID, "/"); i >= 0 {
+		regionID, clusterID = clusterID[:i], clusterID[i+1:]
+	}
+	if regionID == "" {
+		return cloud.ClusterInfo{}, &cloud.MissingInputError{Flags: []string{"region_id"}}
+	}
+	return cloud.ClusterInfo{ID: regionID + "/" + clusterID, Name: clusterID, RegionID: regionID}, nil
+}
+
+// regionCluster a listCluster of the cluster ids REGION/NAME
+type regionCluster struct {
+	listCluster
+}
+
+func (c *regionCluster) ListCluster() ([]cloud.ClusterInfo, error) {
+	clusters, err := c.listCluster.ListCluster()
+	for i := range clusters {
+		clusters[i].ID = c.regionID + "/" + clusters[i].Name
+	}
+	return clusters, err
+}
+
+func (c *regionCluster) GetKubeConfig(clusterID string) (string, error) {
+	return c.listCluster.GetKubeConfig(clusterID[strings.LastIndex(clusterID, "/")+1:])
+}
+
+// runCloudCommand run kubecm cloud with the args on a copy of the test kubeconfig
+func runCloudCommand(t *testing.T, args ...string) (*clientcmdapi.Config, error) {
+	t.Helper()
+	cc := &CloudCommand{}
+	cc.Init()
+	cc.command.SetArgs(args)
+	cc.command.SilenceUsage = true
+	cc.command.SilenceErrors = true
+	err := cc.command.Execute()
+	config, loadErr := clientcmd.LoadFromFile(cfgFile)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	return config, err
+}
+
+func Test_runCloudAdd_nonInteractive(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	registerTestProvider(t, "one", "two", "broken")
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
 	}
+
+	os.Unsetenv("TEST_CLOUD_KEY")
+	_, err := runCloudCommand(t, "add", "--non-interactive", "--provider", "test", "--region_id", "r", "--all", "--cover")
+	var missing *cloud.MissingInputError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Env, []string{"TEST_CLOUD_KEY"}) {
+		t.Fatalf("runCloudAdd() without credentials error = %v", err)
+	}
+
+	t.Setenv("TEST_CLOUD_KEY", "key")
 	tests := []struct {
-		name  string
-		args  args
-		want  string
-		want1 string
+		name    string
+		args    []string
+		wantErr string
 	}{
-		{
-			name: "err_input",
-			args: args{
-				num: -1,
-			},
-			want:  "",
-			want1: "",
-		},
-		{
-			name: "ali_env",
-			args: args{
-				num: 0,
-			},
-			want:  "aliyun_env_id",
-			want1: "aliyun_env_sec",
-		},
-		{
-			name: "ten_env",
-			args: args{
-				num: 1,
-			},
-			want:  "ten_env_id",
-			want1: "ten_env_sec",
-		},
+		{name: "provider", args: []string{"--region_id", "r", "--all", "--cover"}, wantErr: "--provider is required in non-interactive mode"},
+		{name: "region", args: []string{"--provider", "test", "--all", "--cover"}, wantErr: "--region_id is required in non-interactive mode"},
+		{name: "cluster", args: []string{"--provider", "test", "--region_id", "r", "--cover"}, wantErr: "--cluster_id or --all is required in non-interactive mode"},
+		{name: "cover", args: []string{"--provider", "test", "--region_id", "r", "--all"}, wantErr: "--cover is required in non-interactive mode"},
+		{name: "all and cluster", args: []string{"--provider", "test", "--all", "--cluster_id", "one"}, wantErr: "--all can not be used with --cluster_id"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			switch tt.name {
-			case "ali_env":
-				os.Setenv("ACCESS_KEY_ID", "aliyun_env_id")
-				os.Setenv("ACCESS_KEY_SECRET", "aliyun_env_sec")
-			case "ten_env":
-				os.Setenv("TENCENTCLOUD_SECRET_ID", "ten_env_id")
-				os.Setenv("TENCENTCLOUD_SECRET_KEY", "ten_env_sec")
-			}
-			got, got1 := checkEnvForSecret(tt.args.num)
-			if got != tt.want {
-				t.Errorf("checkEnvForSecret() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("checkEnvForSecret() got1 = %v, want %v", got1, tt.want1)
+			_, err := runCloudCommand(t, append([]string{"add", "--non-interactive"}, tt.args...)...)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("runCloudAdd() error = %v, want %s", err, tt.wantErr)
 			}
 		})
 	}
+
+	config, err := runCloudCommand(t, "add", "--non-interactive", "--provider", "test", "--region_id", "r", "--all", "--cover")
+	if err == nil || err.Error() != "failed to add 1 of 3 clusters: broken" {
+		t.Errorf("runCloudAdd() --all error = %v", err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if ctx, ok := config.Contexts[name]; !ok || config.Clusters[ctx.Cluster].Server != "https://"+name+".example.org" {
+			t.Errorf("runCloudAdd() --all did not add context %s: %v", name, config.Contexts)
+		}
+	}
+
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ContextSource{Context: "one", Provider: "test", ClusterID: "one", RegionID: "r"}
+	if metadata["one"] == nil || metadata["one"].Source.ImportedAt.IsZero() {
+		t.Fatalf("runCloudAdd() recorded %+v without import time", metadata["one"])
+	}
+	metadata["one"].Source.ImportedAt = time.Time{}
+	if metadata["one"] == nil || !reflect.DeepEqual(metadata["one"].Source, want) {
+		t.Errorf("runCloudAdd() recorded %+v, want %+v", metadata["one"], want)
+	}
+
+	// the contexts that already exist are skipped instead of asking to rename them
+	config, err = runCloudCommand(t, "add", "--non-interactive", "--provider", "test", "--region_id", "r", "--all", "--cover")
+	if err == nil || len(config.Contexts) != len(appendMergeConfig.Contexts)+2 {
+		t.Errorf("runCloudAdd() again got %d contexts, error = %v", len(config.Contexts), err)
+	}
+}
+
+func Test_newCloudClient(t *testing.T) {
+	t.Setenv("TEST_CLOUD_KEY", "key")
+	provider := &cloud.Provider{
+		Name:        "Test",
+		Credentials: []cloud.Credential{{Env: "TEST_CLOUD_KEY", Prompt: "Test Key"}},
+		Regional:    true,
+		New: func(s *cloud.Session) (cloud.Cluster, error) {
+			if s.Credentials["TEST_CLOUD_KEY"] != "key" {
+				return nil, errors.New("wrong key")
+			}
+			return &testCluster{regionID: s.RegionID}, nil
+		},
+	}
+	prompter := &testPrompter{selects: map[string]int{"Select Region ID": 1}}
+	client, err := newCloudClient(provider, &cloud.Session{Prompter: prompter})
+	if err != nil {
+		t.Fatalf("newCloudClient() error = %v", err)
+	}
+	if got := client.(*testCluster).regionID; got != "region-b" {
+		t.Errorf("newCloudClient() region = %v, want the selected region-b", got)
+	}
+	prompter.asked = nil
+	if client, err = newCloudClient(provider, &cloud.Session{RegionID: "region-c", Prompter: prompter}); err != nil || client.(*testCluster).regionID != "region-c" {
+		t.Errorf("newCloudClient() with --region_id got = %+v, error = %v", client, err)
+	}
+	if len(prompter.asked) != 0 {
+		t.Errorf("newCloudClient() with --region_id asked %v", prompter.asked)
+	}
+
+	provider.RegionPrompt = "Select Region Name"
+	prompter = &testPrompter{selects: map[string]int{"Select Region Name": 0}}
+	if client, err = newCloudClient(provider, &cloud.Session{Prompter: prompter}); err != nil || client.(*testCluster).regionID != "region-a" {
+		t.Errorf("newCloudClient() with a region prompt got = %+v, error = %v", client, err)
+	}
+}
+
+func Test_runCloudAdd_unsupportedProvider(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	defer func(f func() bool) { stdinIsTerminal = f }(stdinIsTerminal)
+	stdinIsTerminal = func() bool { return true }
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	// cloud add only prints the supported aliases, the non-interactive mode fails
+	if _, err := runCloudCommand(t, "add", "--provider", "nope"); err != nil {
+		t.Errorf("runCloudAdd() of an unsupported provider error = %v", err)
+	}
+	if _, err := runCloudCommand(t, "add", "--provider", "nope", "--non-interactive", "--cover"); err == nil {
+		t.Errorf("runCloudAdd() of an unsupported provider in non-interactive mode should fail")
+	}
+	if _, err := runCloudCommand(t, "list", "--provider", "nope"); err == nil {
+		t.Errorf("runCloudList() of an unsupported provider should fail")
+	}
+}
+
+func Test_clusterByID(t *testing.T) {
+	provider := &cloud.Provider{Name: "Test", ParseClusterID: parseRegionClusterID}
+	client := &testCluster{}
+	prompter := &testPrompter{selects: map[string]int{"Select Region ID": 1}}
+	session := &cloud.Session{Prompter: prompter}
+	cluster, err := clusterByID(provider, client, session, "one")
+	if err != nil || cluster.ID != "region-b/one" || cluster.RegionID != "region-b" {
+		t.Errorf("clusterByID() of a name got %+v, error = %v, want the selected region-b", cluster, err)
+	}
+	if session.RegionID != "region-b" {
+		t.Errorf("clusterByID() did not set the selected region in the session")
+	}
+
+	prompter.asked = nil
+	cluster, err = clusterByID(provider, client, &cloud.Session{Prompter: prompter}, "region-a/one")
+	if err != nil || cluster.ID != "region-a/one" || len(prompter.asked) != 0 {
+		t.Errorf("clusterByID() of an id with its region got %+v, error = %v, asked %v", cluster, err, prompter.asked)
+	}
+
+	var missing *cloud.MissingInputError
+	if _, err = clusterByID(provider, client, &cloud.Session{}, "one"); !errors.As(err, &missing) {
+		t.Errorf("clusterByID() of a name in non-interactive mode error = %v, want a missing --region_id", err)
+	}
+
+	// the id is kept by the providers not parsing it
+	provider.ParseClusterID = nil
+	if cluster, err = clusterByID(provider, client, &cloud.Session{RegionID: "r"}, "c-1"); err != nil || cluster.ID != "c-1" || cluster.RegionID != "r" {
+		t.Errorf("clusterByID() without ParseClusterID got %+v, error = %v", cluster, err)
+	}
 }
@@ -23,6 +23,14 @@ func NewBaseCommand() *BaseCommand {
 		&ExportCommand{},     // export command
 		&DocsCommand{},       // docs command
 		&GpuCommand{},        // gpu command
+		&RestoreCommand{},    // restore command
+		&ShellCommand{},      // shell command
+		&HistoryCommand{},    // history command
+		&TagCommand{},        // tag command
+		&DoctorCommand{},     // doctor command
+		&CertsCommand{},      // certs command
+		&InfoCommand{},       // info command
+		&RefreshCommand{},    // refresh command
 	)
 
 	return baseCmd
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// keep backups and other kubecm state out of the home directory
+	home, err := os.MkdirTemp("", "kubecm-home-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("KUBECM_HOME", home)
+	code := m.Run()
+	os.RemoveAll(home)
+	os.Exit(code)
+}
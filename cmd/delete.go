@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
 	"slices"
 
 	"github.com/spf13/cobra"
@@ -35,29 +36,26 @@ func (dc *DeleteCommand) runDelete(command *cobra.Command, args []string) error
 	if err != nil {
 		return err
 	}
+	ctxs := args
 	if len(args) == 0 {
 		confirm, kubeName, err := selectDeleteContext(config)
 		if err != nil {
 			return err
 		}
-		if confirm == "True" {
-			err = deleteContext([]string{kubeName}, config)
-			if err != nil {
-				return err
-			}
-		} else {
+		if confirm != "True" {
 			return errors.New("nothing deleted！")
 		}
-	} else {
-		err = deleteContext(args, config)
-		if err != nil {
-			return err
-		}
+		ctxs = []string{kubeName}
 	}
-	err = WriteConfig(true, cfgFile, config)
-	if err != nil {
+	err = ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+		return deleteContext(ctxs, config)
+	})
+	if err != nil || dryRun {
 		return err
 	}
+	if err = deleteMetadata(cfgFile, ctxs...); err != nil {
+		printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to delete the tags of the contexts: %v\n", err))
+	}
 	return nil
 }
 
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// redacted replace the value of secret fields in diff output
+const redacted = "<redacted>"
+
+// field one line of a kubeconfig entry in diff output
+type field struct {
+	Name  string
+	Value string
+	// Secret values are only compared, never printed
+	Secret bool
+}
+
+// String return the value printed in diff output
+func (f field) String() string {
+	if f.Secret {
+		return redacted
+	}
+	return f.Value
+}
+
+var (
+	addColor    = color.New(color.FgGreen)
+	removeColor = color.New(color.FgRed)
+	changeColor = color.New(color.FgYellow)
+)
+
+// printConfigDiff print the semantic diff of contexts, clusters and users between two kubeconfigs.
+// It returns false when there is no difference.
+func printConfigDiff(out io.Writer, oldConfig, newConfig *clientcmdapi.Config) bool {
+	changed := false
+	if oldConfig.CurrentContext != newConfig.CurrentContext {
+		changeColor.Fprintln(out, "~ current-context")
+		removeColor.Fprintf(out, "    - %s\n", oldConfig.CurrentContext)
+		addColor.Fprintf(out, "    + %s\n", newConfig.CurrentContext)
+		changed = true
+	}
+	oldContexts, newContexts := make(map[string][]field), make(map[string][]field)
+	for key, obj := range oldConfig.Contexts {
+		oldContexts[key] = contextFields(obj)
+	}
+	for key, obj := range newConfig.Contexts {
+		newContexts[key] = contextFields(obj)
+	}
+	changed = printEntriesDiff(out, "context", oldContexts, newContexts) || changed
+
+	oldClusters, newClusters := make(map[string][]field), make(map[string][]field)
+	for key, obj := range oldConfig.Clusters {
+		oldClusters[key] = clusterFields(obj)
+	}
+	for key, obj := range newConfig.Clusters {
+		newClusters[key] = clusterFields(obj)
+	}
+	changed = printEntriesDiff(out, "cluster", oldClusters, newClusters) || changed
+
+	oldUsers, newUsers := make(map[string][]field), make(map[string][]field)
+	for key, obj := range oldConfig.AuthInfos {
+		oldUsers[key] = authInfoFields(obj)
+	}
+	for key, obj := range newConfig.AuthInfos {
+		newUsers[key] = authInfoFields(obj)
+	}
+	changed = printEntriesDiff(out, "user", oldUsers, newUsers) || changed
+	return changed
+}
+
+func printEntriesDiff(out io.Writer, kind string, oldEntries, newEntries map[string][]field) bool {
+	keys := make([]string, 0, len(oldEntries)+len(newEntries))
+	for key := range oldEntries {
+		keys = append(keys, key)
+	}
+	for key := range newEntries {
+		if _, ok := oldEntries[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changed := false
+	for _, key := range keys {
+		oldFields, inOld := oldEntries[key]
+		newFields, inNew := newEntries[key]
+		switch {
+		case !inOld:
+			addColor.Fprintf(out, "+ %s %s\n", kind, key)
+			for _, f := range newFields {
+				addColor.Fprintf(out, "    + %s: %s\n", f.Name, f)
+			}
+			changed = true
+		case !inNew:
+			removeColor.Fprintf(out, "- %s %s\n", kind, key)
+			for _, f := range oldFields {
+				removeColor.Fprintf(out, "    - %s: %s\n", f.Name, f)
+			}
+			changed = true
+		default:
+			lines := fieldsDiff(oldFields, newFields)
+			if len(lines) == 0 {
+				continue
+			}
+			changeColor.Fprintf(out, "~ %s %s\n", kind, key)
+			for _, line := range lines {
+				switch {
+				case strings.HasPrefix(line, "-"):
+					removeColor.Fprintf(out, "    %s\n", line)
+				case strings.HasPrefix(line, "~"):
+					changeColor.Fprintf(out, "    %s\n", line)
+				default:
+					addColor.Fprintf(out, "    %s\n", line)
+				}
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// fieldsDiff return the removed, changed and added lines between two field lists, a changed secret
+// is one line without its values
+func fieldsDiff(oldFields, newFields []field) []string {
+	oldValues, newValues := make(map[string]field), make(map[string]field)
+	var names []string
+	for _, f := range oldFields {
+		oldValues[f.Name] = f
+		names = append(names, f.Name)
+	}
+	for _, f := range newFields {
+		newValues[f.Name] = f
+		if _, ok := oldValues[f.Name]; !ok {
+			names = append(names, f.Name)
+		}
+	}
+	var lines []string
+	for _, name := range names {
+		oldValue, inOld := oldValues[name]
+		newValue, inNew := newValues[name]
+		if inOld && inNew && oldValue.Value == newValue.Value {
+			continue
+		}
+		if inOld && inNew && newValue.Secret {
+			lines = append(lines, fmt.Sprintf("~ %s: %s (changed)", name, newValue))
+			continue
+		}
+		if inOld {
+			lines = append(lines, fmt.Sprintf("- %s: %s", name, oldValue))
+		}
+		if inNew {
+			lines = append(lines, fmt.Sprintf("+ %s: %s", name, newValue))
+		}
+	}
+	return lines
+}
+
+func contextFields(ctx *clientcmdapi.Context) []field {
+	var fields []field
+	fields = appendField(fields, "cluster", ctx.Cluster)
+	fields = appendField(fields, "user", ctx.AuthInfo)
+	fields = appendField(fields, "namespace", ctx.Namespace)
+	return fields
+}
+
+func clusterFields(cluster *clientcmdapi.Cluster) []field {
+	var fields []field
+	fields = appendField(fields, "server", cluster.Server)
+	fields = appendField(fields, "tls-server-name", cluster.TLSServerName)
+	fields = appendField(fields, "certificate-authority", cluster.CertificateAuthority)
+	fields = appendSecret(fields, "certificate-authority-data", string(cluster.CertificateAuthorityData))
+	fields = appendField(fields, "proxy-url", cluster.ProxyURL)
+	if cluster.InsecureSkipTLSVerify {
+		fields = appendField(fields, "insecure-skip-tls-verify", strconv.FormatBool(cluster.InsecureSkipTLSVerify))
+	}
+	return fields
+}
+
+func authInfoFields(authInfo *clientcmdapi.AuthInfo) []field {
+	var fields []field
+	fields = appendField(fields, "client-certificate", authInfo.ClientCertificate)
+	fields = appendSecret(fields, "client-certificate-data", string(authInfo.ClientCertificateData))
+	fields = appendField(fields, "client-key", authInfo.ClientKey)
+	fields = appendSecret(fields, "client-key-data", string(authInfo.ClientKeyData))
+	fields = appendSecret(fields, "token", authInfo.Token)
+	fields = appendField(fields, "token-file", authInfo.TokenFile)
+	fields = appendField(fields, "as", authInfo.Impersonate)
+	fields = appendField(fields, "username", authInfo.Username)
+	fields = appendSecret(fields, "password", authInfo.Password)
+	if authInfo.AuthProvider != nil {
+		fields = appendField(fields, "auth-provider", authInfo.AuthProvider.Name)
+	}
+	if authInfo.Exec != nil {
+		fields = appendField(fields, "exec", strings.TrimSpace(authInfo.Exec.Command+" "+strings.Join(authInfo.Exec.Args, " ")))
+	}
+	return fields
+}
+
+func appendField(fields []field, name, value string) []field {
+	if value == "" {
+		return fields
+	}
+	return append(fields, field{Name: name, Value: value})
+}
+
+// appendSecret append a secret field, the value is only used to detect changes and never printed
+func appendSecret(fields []field, name, value string) []field {
+	if value == "" {
+		return fields
+	}
+	return append(fields, field{Name: name, Value: value, Secret: true})
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_printConfigDiff(t *testing.T) {
+	color.NoColor = true
+	newConfig := appendMergeConfig.DeepCopy()
+	delete(newConfig.Contexts, "federal-context")
+	newConfig.Contexts["root-context"].Namespace = "new-ns"
+	newConfig.AuthInfos["black-user"].Token = "new-token"
+	newConfig.Clusters["dog-cluster"] = &clientcmdapi.Cluster{Server: "http://dog.org:8080"}
+	newConfig.CurrentContext = "root-context"
+
+	out := new(bytes.Buffer)
+	if !printConfigDiff(out, &appendMergeConfig, newConfig) {
+		t.Fatal("printConfigDiff() reported no changes")
+	}
+	got := out.String()
+	for _, want := range []string{
+		"~ current-context\n",
+		"- context federal-context\n",
+		"~ context root-context\n    - namespace: saw-ns\n    + namespace: new-ns\n",
+		"+ cluster dog-cluster\n    + server: http://dog.org:8080\n",
+		"~ user black-user\n    ~ token: <redacted> (changed)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("printConfigDiff() output missing %q, got:\n%s", want, got)
+		}
+	}
+	for _, secret := range []string{"black-token", "new-token"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("printConfigDiff() leaked secret %q", secret)
+		}
+	}
+
+	out.Reset()
+	if printConfigDiff(out, &appendMergeConfig, appendMergeConfig.DeepCopy()) || out.Len() != 0 {
+		t.Errorf("printConfigDiff() got changes for identical configs: %s", out.String())
+	}
+}
+
+func TestModifyConfigDryRun(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(appendMergeConfig, file); err != nil {
+		t.Fatal(err)
+	}
+	dryRun = true
+	defer func() { dryRun = false }()
+	_, err := ModifyConfig(file, func(config *clientcmdapi.Config) error {
+		return deleteContext([]string{"root-context"}, config)
+	})
+	if err != nil {
+		t.Fatalf("ModifyConfig() error = %v", err)
+	}
+	got, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Contexts["root-context"]; !ok {
+		t.Errorf("ModifyConfig() wrote the kubeconfig in dry run mode")
+	}
+}
+
+func TestWriteConfigDryRun(t *testing.T) {
+	color.NoColor = true
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(appendMergeConfig, cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	out := appendMergeConfig.DeepCopy()
+	out.Contexts["new-context"] = &clientcmdapi.Context{Cluster: "pig-cluster", AuthInfo: "black-user"}
+	// the ./kubecm.config written without --cover already holds the result
+	if err = clientcmd.WriteToFile(*out, "kubecm.config"); err != nil {
+		t.Fatal(err)
+	}
+
+	dryRun = true
+	defer func() { dryRun = false }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = WriteConfig(false, cfgFile, &appendMergeConfig, out)
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+	if !strings.Contains(string(printed), "「kubecm.config」 is not changed") || !strings.Contains(string(printed), "No changes.") {
+		t.Errorf("WriteConfig() without cover did not diff against kubecm.config, got:\n%s", printed)
+	}
+}
@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bndr/gotabulate"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// severities of the problems found by kubecm doctor, most severe first
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Problem one finding of kubecm doctor
+type Problem struct {
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Message  string `json:"message"`
+	Fixable  bool   `json:"fixable"`
+	Fixed    bool   `json:"fixed,omitempty"`
+	// fix repairs the problem in the config, nil when it can not be repaired safely
+	fix func(config *clientcmdapi.Config)
+}
+
+// DoctorCommand doctor cmd struct
+type DoctorCommand struct {
+	BaseCommand
+}
+
+// Init DoctorCommand
+func (dc *DoctorCommand) Init() {
+	dc.command = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the integrity of kubeconfig",
+		Long: `
+Check the integrity of kubeconfig: lapsed contexts, orphan and duplicate clusters and users,
+unreadable or malformed certificates and keys, expired client certificates,
+missing exec plugins and a missing current-context.
+It exits with code 1 when a problem of severity error is found.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dc.runDoctor(cmd, args)
+		},
+		Example:      doctorExample(),
+		SilenceUsage: true,
+	}
+	dc.command.Flags().Bool("fix", false, "repair the problems that can be repaired safely")
+	dc.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml")
+	dc.AddCommands(&DocsCommand{})
+}
+
+func (dc *DoctorCommand) runDoctor(cmd *cobra.Command, args []string) error {
+	output, _ := dc.command.Flags().GetString("output")
+	if output != "" && output != OutputJSON && output != OutputYAML {
+		return fmt.Errorf("unsupported output format %q, the available values are: json, yaml", output)
+	}
+	fix, _ := dc.command.Flags().GetBool("fix")
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(cfgFile)
+	problems := diagnose(config, dir, time.Now())
+	if fix && slices.ContainsFunc(problems, func(p Problem) bool { return p.Fixable }) {
+		var removed []string
+		_, err = ModifyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+			oldConfig := config.DeepCopy()
+			problems = fixProblems(config, dir, time.Now())
+			removed = removedContexts(oldConfig, config)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(removed) > 0 && !dryRun {
+			if err = deleteMetadata(cfgFile, removed...); err != nil {
+				printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to delete the tags of the contexts: %v\n", err))
+			}
+		}
+	}
+	if output != "" {
+		if problems == nil {
+			problems = []Problem{}
+		}
+		err = printStructured(os.Stdout, output, problems)
+	} else {
+		err = printProblems(os.Stdout, problems)
+	}
+	if err != nil {
+		return err
+	}
+	var errorCount int
+	for _, p := range problems {
+		if p.Severity == SeverityError && !p.Fixed {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("found %d problems of severity error in 「%s」", errorCount, cfgFile)
+	}
+	return nil
+}
+
+// fixProblems repair the fixable problems of config, until the repairs reveal no more of them,
+// e.g. a cluster becomes orphan once its lapsed context is removed.
+// It returns the repaired and the remaining problems.
+func fixProblems(config *clientcmdapi.Config, dir string, now time.Time) []Problem {
+	var fixed []Problem
+	remaining := diagnose(config, dir, now)
+	for round := 0; round < 3 && slices.ContainsFunc(remaining, func(p Problem) bool { return p.Fixable }); round++ {
+		for _, p := range remaining {
+			if p.fix != nil {
+				p.fix(config)
+				p.Fixed = !dryRun
+				fixed = append(fixed, p)
+			}
+		}
+		remaining = diagnose(config, dir, now)
+	}
+	return sortProblems(append(fixed, remaining...))
+}
+
+// diagnose return the problems of config sorted by severity, relative file paths are resolved against dir
+func diagnose(config *clientcmdapi.Config, dir string, now time.Time) []Problem {
+	var problems []Problem
+	if config.CurrentContext != "" {
+		if _, ok := config.Contexts[config.CurrentContext]; !ok {
+			problems = append(problems, Problem{
+				Severity: SeverityError, Kind: "current-context", Name: config.CurrentContext,
+				Message: "current-context does not exist, run `kubecm switch` to choose one",
+			})
+		}
+	}
+	problems = append(problems, diagnoseReferences(config)...)
+	problems = append(problems, diagnoseDuplicateClusters(config)...)
+	for name, cluster := range config.Clusters {
+		problems = append(problems, diagnoseCluster(name, cluster, dir)...)
+	}
+	for name, authInfo := range config.AuthInfos {
+		problems = append(problems, diagnoseAuthInfo(name, authInfo, dir, now)...)
+	}
+	return sortProblems(problems)
+}
+
+// sortProblems sort the problems by severity, kind and name
+func sortProblems(problems []Problem) []Problem {
+	rank := map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.Severity != b.Severity {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Message < b.Message
+	})
+	return problems
+}
+
+// diagnoseReferences find contexts pointing to missing clusters or users, and clusters or users no context uses
+func diagnoseReferences(config *clientcmdapi.Config) []Problem {
+	var problems []Problem
+	for name, ctx := range config.Contexts {
+		_, clusterOK := config.Clusters[ctx.Cluster]
+		_, userOK := config.AuthInfos[ctx.AuthInfo]
+		if clusterOK && userOK {
+			continue
+		}
+		message := fmt.Sprintf("cluster 「%s」 does not exist", ctx.Cluster)
+		if !userOK {
+			message = fmt.Sprintf("user 「%s」 does not exist", ctx.AuthInfo)
+		}
+		problems = append(problems, Problem{
+			Severity: SeverityError, Kind: "context", Name: name, Message: message + ", the context is removed by --fix",
+			Fixable: true, fix: func(config *clientcmdapi.Config) { delete(config.Contexts, name) },
+		})
+	}
+	for name := range config.Clusters {
+		if !contextUses(config, name, "") {
+			problems = append(problems, Problem{
+				Severity: SeverityWarning, Kind: "cluster", Name: name, Message: "no context uses the cluster",
+				Fixable: true, fix: func(config *clientcmdapi.Config) { delete(config.Clusters, name) },
+			})
+		}
+	}
+	for name := range config.AuthInfos {
+		if !contextUses(config, "", name) {
+			problems = append(problems, Problem{
+				Severity: SeverityWarning, Kind: "user", Name: name, Message: "no context uses the user",
+				Fixable: true, fix: func(config *clientcmdapi.Config) { delete(config.AuthInfos, name) },
+			})
+		}
+	}
+	return problems
+}
+
+// diagnoseDuplicateClusters find clusters with the same server and CA under different names,
+// they are merged by --fix when all their fields are equal
+func diagnoseDuplicateClusters(config *clientcmdapi.Config) []Problem {
+	groups := make(map[string][]string)
+	for name, cluster := range config.Clusters {
+		if cluster.Server == "" {
+			continue
+		}
+		key := strings.Join([]string{cluster.Server, cluster.CertificateAuthority, string(cluster.CertificateAuthorityData)}, "\x00")
+		groups[key] = append(groups[key], name)
+	}
+	var problems []Problem
+	for _, names := range groups {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		keep := names[0]
+		for _, name := range names[1:] {
+			problem := Problem{
+				Severity: SeverityInfo, Kind: "cluster", Name: name,
+				Message: fmt.Sprintf("same server and CA as cluster 「%s」", keep),
+			}
+			if slices.Equal(clusterFields(config.Clusters[keep]), clusterFields(config.Clusters[name])) {
+				problem.Message += ", the contexts are moved to it by --fix"
+				problem.Fixable = true
+				problem.fix = func(config *clientcmdapi.Config) {
+					if _, ok := config.Clusters[keep]; !ok {
+						return
+					}
+					for _, ctx := range config.Contexts {
+						if ctx.Cluster == name {
+							ctx.Cluster = keep
+						}
+					}
+					delete(config.Clusters, name)
+				}
+			}
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+func diagnoseCluster(name string, cluster *clientcmdapi.Cluster, dir string) []Problem {
+	var problems []Problem
+	add := func(severity, message string) {
+		problems = append(problems, Problem{Severity: severity, Kind: "cluster", Name: name, Message: message})
+	}
+	if cluster.CertificateAuthority != "" {
+		data, err := readConfigFile(cluster.CertificateAuthority, dir)
+		if err != nil {
+			add(SeverityError, fmt.Sprintf("certificate-authority is not readable: %v", err))
+		} else if _, err = parseCertificates(data); err != nil {
+			add(SeverityError, fmt.Sprintf("certificate-authority %s: %v", cluster.CertificateAuthority, err))
+		}
+	}
+	if len(cluster.CertificateAuthorityData) > 0 {
+		if _, err := parseCertificates(cluster.CertificateAuthorityData); err != nil {
+			add(SeverityError, fmt.Sprintf("certificate-authority-data: %v", err))
+		}
+	}
+	return problems
+}
+
+func diagnoseAuthInfo(name string, authInfo *clientcmdapi.AuthInfo, dir string, now time.Time) []Problem {
+	var problems []Problem
+	add := func(severity, message string) {
+		problems = append(problems, Problem{Severity: severity, Kind: "user", Name: name, Message: message})
+	}
+	checkCertificates := func(field string, data []byte) {
+		certs, err := parseCertificates(data)
+		if err != nil {
+			add(SeverityError, fmt.Sprintf("%s: %v", field, err))
+			return
+		}
+		if now.After(certs[0].NotAfter) {
+			add(SeverityError, fmt.Sprintf("client certificate expired at %s", certs[0].NotAfter.Format(time.RFC3339)))
+		}
+	}
+	checkKey := func(field string, data []byte) {
+		if err := checkPrivateKey(data); err != nil {
+			add(SeverityError, fmt.Sprintf("%s: %v", field, err))
+		}
+	}
+	if authInfo.ClientCertificate != "" {
+		data, err := readConfigFile(authInfo.ClientCertificate, dir)
+		if err != nil {
+			add(SeverityError, fmt.Sprintf("client-certificate is not readable: %v", err))
+		} else {
+			checkCertificates("client-certificate "+authInfo.ClientCertificate, data)
+		}
+	}
+	if len(authInfo.ClientCertificateData) > 0 {
+		checkCertificates("client-certificate-data", authInfo.ClientCertificateData)
+	}
+	if authInfo.ClientKey != "" {
+		data, err := readConfigFile(authInfo.ClientKey, dir)
+		if err != nil {
+			add(SeverityError, fmt.Sprintf("client-key is not readable: %v", err))
+		} else {
+			checkKey("client-key "+authInfo.ClientKey, data)
+		}
+	}
+	if len(authInfo.ClientKeyData) > 0 {
+		checkKey("client-key-data", authInfo.ClientKeyData)
+	}
+	if authInfo.TokenFile != "" {
+		if _, err := readConfigFile(authInfo.TokenFile, dir); err != nil {
+			add(SeverityError, fmt.Sprintf("tokenFile is not readable: %v", err))
+		}
+	}
+	if authInfo.Exec != nil && authInfo.Exec.Command != "" {
+		if _, err := exec.LookPath(authInfo.Exec.Command); err != nil {
+			message := fmt.Sprintf("exec plugin %s is not found in PATH", authInfo.Exec.Command)
+			if authInfo.Exec.InstallHint != "" {
+				message += ": " + strings.TrimSpace(authInfo.Exec.InstallHint)
+			}
+			add(SeverityError, message)
+		}
+	}
+	return problems
+}
+
+// readConfigFile read a file referenced by kubeconfig, relative paths are relative to the kubeconfig
+func readConfigFile(path, dir string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return os.ReadFile(path)
+}
+
+// parseCertificates parse the PEM encoded certificates, the first one is the leaf
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("malformed certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// checkPrivateKey check that data holds a PEM encoded private key
+func checkPrivateKey(data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		return errors.New("no PEM encoded private key found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		_, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		return malformedKey(err)
+	case "EC PRIVATE KEY":
+		_, err := x509.ParseECPrivateKey(block.Bytes)
+		return malformedKey(err)
+	case "PRIVATE KEY":
+		_, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		return malformedKey(err)
+	}
+	return nil
+}
+
+func malformedKey(err error) error {
+	if err != nil {
+		return fmt.Errorf("malformed private key: %v", err)
+	}
+	return nil
+}
+
+func printProblems(out io.Writer, problems []Problem) error {
+	if len(problems) == 0 {
+		printString(out, fmt.Sprintf("No problems found in 「%s」\n", cfgFile))
+		return nil
+	}
+	var table [][]string
+	for _, p := range problems {
+		fix := ""
+		switch {
+		case p.Fixed:
+			fix = "fixed"
+		case p.Fixable:
+			fix = "--fix"
+		}
+		table = append(table, []string{p.Severity, p.Kind, p.Name, p.Message, fix})
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"SEVERITY", "KIND", "NAME", "PROBLEM", "FIX"})
+	tabulate.SetWrapStrings(true)
+	tabulate.SetMaxCellSize(60)
+	tabulate.SetWrapDelimiter(' ')
+	tabulate.SetAlign("left")
+	fmt.Fprintln(out, tabulate.Render("grid", "left"))
+	return nil
+}
+
+func doctorExample() string {
+	return `
+# Check the integrity of kubeconfig
+kubecm doctor
+# Repair the problems that can be repaired safely, e.g. lapsed contexts and orphan clusters and users
+kubecm doctor --fix
+# Preview the repairs
+kubecm doctor --fix --dry-run
+# Output the problems as json for CI
+kubecm doctor -o json
+`
+}
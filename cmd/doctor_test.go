@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newTestCertificate return a self-signed PEM certificate and key valid until notAfter
+func newTestCertificate(t *testing.T, commonName string, notAfter time.Time) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func Test_diagnose(t *testing.T) {
+	now := time.Now()
+	validCert, validKey := newTestCertificate(t, "valid", now.Add(time.Hour))
+	expiredCert, _ := newTestCertificate(t, "expired", now.Add(-time.Hour))
+	config := &clientcmdapi.Config{
+		CurrentContext: "missing-context",
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"pig-cluster":  {Server: "https://pig.org", CertificateAuthorityData: validCert},
+			"pig-copy":     {Server: "https://pig.org", CertificateAuthorityData: validCert},
+			"cow-cluster":  {Server: "https://cow.org", CertificateAuthority: "missing-ca.crt"},
+			"bad-cluster":  {Server: "https://bad.org", CertificateAuthorityData: []byte("not a pem")},
+			"lost-cluster": {Server: "https://lost.org"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"black-user":  {ClientCertificateData: validCert, ClientKeyData: validKey},
+			"old-user":    {ClientCertificateData: expiredCert, ClientKeyData: []byte("not a key")},
+			"exec-user":   {Exec: &clientcmdapi.ExecConfig{Command: "kubecm-no-such-plugin"}},
+			"orphan-user": {Token: "token"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"root-context":    {Cluster: "pig-cluster", AuthInfo: "black-user"},
+			"copy-context":    {Cluster: "pig-copy", AuthInfo: "black-user"},
+			"cow-context":     {Cluster: "cow-cluster", AuthInfo: "old-user"},
+			"bad-context":     {Cluster: "bad-cluster", AuthInfo: "exec-user"},
+			"lapsed-context":  {Cluster: "lost-cluster", AuthInfo: "missing-user"},
+			"lapsed-context2": {Cluster: "missing-cluster", AuthInfo: "black-user"},
+		},
+	}
+	type finding struct {
+		severity, kind, name string
+		fixable              bool
+	}
+	want := []finding{
+		{SeverityError, "cluster", "bad-cluster", false},
+		{SeverityError, "cluster", "cow-cluster", false},
+		{SeverityError, "context", "lapsed-context", true},
+		{SeverityError, "context", "lapsed-context2", true},
+		{SeverityError, "current-context", "missing-context", false},
+		{SeverityError, "user", "exec-user", false},
+		{SeverityError, "user", "old-user", false},
+		{SeverityError, "user", "old-user", false},
+		{SeverityWarning, "user", "orphan-user", true},
+		{SeverityInfo, "cluster", "pig-copy", true},
+	}
+	problems := diagnose(config, t.TempDir(), now)
+	if len(problems) != len(want) {
+		t.Fatalf("diagnose() got %d problems %v, want %d", len(problems), problems, len(want))
+	}
+	for i, p := range problems {
+		got := finding{p.Severity, p.Kind, p.Name, p.Fixable}
+		if got != want[i] {
+			t.Errorf("diagnose() problem %d got = %v %s, want %v", i, got, p.Message, want[i])
+		}
+	}
+
+	problems = fixProblems(config, t.TempDir(), now)
+	for _, name := range []string{"lapsed-context", "lapsed-context2"} {
+		if _, ok := config.Contexts[name]; ok {
+			t.Errorf("fixProblems() did not remove context %s", name)
+		}
+	}
+	if _, ok := config.Clusters["lost-cluster"]; ok {
+		t.Errorf("fixProblems() did not remove the cluster orphaned by the removed context")
+	}
+	if _, ok := config.AuthInfos["orphan-user"]; ok {
+		t.Errorf("fixProblems() did not remove the orphan user")
+	}
+	if _, ok := config.Clusters["pig-copy"]; ok || config.Contexts["copy-context"].Cluster != "pig-cluster" {
+		t.Errorf("fixProblems() did not merge the duplicate cluster")
+	}
+	for _, p := range problems {
+		if p.Fixable != p.Fixed {
+			t.Errorf("fixProblems() problem %v is fixable but not fixed", p)
+		}
+	}
+}
+
+func Test_runDoctor_fixMetadata(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(wrongRootConfig, cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	err := updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+		metadata["root-context"] = &ContextMetadata{Source: &ContextSource{Path: "/tmp/admin.conf"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := &DoctorCommand{}
+	dc.Init()
+	dc.command.SetArgs([]string{"--fix"})
+	dc.command.SilenceUsage = true
+	dc.command.SilenceErrors = true
+	_ = dc.command.Execute()
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.Contexts["root-context"]; ok {
+		t.Fatalf("runDoctor() --fix kept the dangling root-context")
+	}
+	if metadata, _ := loadMetadata(cfgFile); metadata["root-context"] != nil {
+		t.Errorf("runDoctor() --fix kept the metadata of root-context")
+	}
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/utils"
+	"github.com/bndr/gotabulate"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// historyLimit is the number of history entries kept for each kubeconfig
+const historyLimit = 100
+
+// HistoryEntry one record of the switch history
+type HistoryEntry struct {
+	Context   string    `json:"context"`
+	Namespace string    `json:"namespace,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// HistoryCommand history cmd struct
+type HistoryCommand struct {
+	BaseCommand
+}
+
+// Init HistoryCommand
+func (hc *HistoryCommand) Init() {
+	hc.command = &cobra.Command{
+		Use:   "history",
+		Short: "Show and switch to recently used contexts",
+		Long: `
+Show and switch to recently used contexts
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return hc.runHistory(cmd, args)
+		},
+		Example: historyExample(),
+	}
+	hc.command.Flags().BoolP("list", "l", false, "list the recently used contexts")
+	hc.AddCommands(&DocsCommand{})
+}
+
+func (hc *HistoryCommand) runHistory(command *cobra.Command, args []string) error {
+	entries, err := recentEntries(cfgFile)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("no switch history found")
+	}
+	list, _ := hc.command.Flags().GetBool("list")
+	if list {
+		return printHistoryTable(entries)
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	from := config.CurrentContext
+	name := entries[selectHistory(entries, "Select Recent Kube Context")].Context
+	if _, err = handleQuickSwitch(config, name); err != nil {
+		return err
+	}
+	if err = confirmProtected(name, false); err != nil {
+		return err
+	}
+	return switchContext(config, from, name, "")
+}
+
+// historyFile return the switch history file of the kubeconfig file
+func historyFile(file string) string {
+	return filepath.Join(kubecmHome(), "history", pathHash(file)+".json")
+}
+
+// loadHistory return the switch history of the kubeconfig file, oldest first
+func loadHistory(file string) ([]HistoryEntry, error) {
+	content, err := os.ReadFile(historyFile(file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []HistoryEntry
+	if err = json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("invalid history file %s: %v", historyFile(file), err)
+	}
+	return entries, nil
+}
+
+// recordHistory append entries to the switch history of the kubeconfig file
+func recordHistory(file string, records ...HistoryEntry) error {
+	path := historyFile(file)
+	unlock, err := utils.LockFile(lockPath(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := loadHistory(file)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, record := range records {
+		if record.Time.IsZero() {
+			record.Time = now
+		}
+		entries = append(entries, record)
+	}
+	if len(entries) > historyLimit {
+		entries = entries[len(entries)-historyLimit:]
+	}
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, content, 0600)
+}
+
+// recentEntries return the latest entry of each context in the history, newest first
+func recentEntries(file string) ([]HistoryEntry, error) {
+	entries, err := loadHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	var recent []HistoryEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !slices.ContainsFunc(recent, func(e HistoryEntry) bool { return e.Context == entries[i].Context }) {
+			recent = append(recent, entries[i])
+		}
+	}
+	return recent, nil
+}
+
+// recentContexts return the recently used context names, newest first
+func recentContexts(file string) []string {
+	entries, _ := recentEntries(file)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Context)
+	}
+	return names
+}
+
+// previousContext return the latest context in the history other than current, like `cd -`
+func previousContext(file, current string) (string, error) {
+	for _, name := range recentContexts(file) {
+		if name != current {
+			return name, nil
+		}
+	}
+	return "", errors.New("no previous context found in the switch history")
+}
+
+// recentNamespaces return the recently used namespaces of the context, newest first
+func recentNamespaces(file, context string) []string {
+	entries, _ := loadHistory(file)
+	var namespaces []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Context != context {
+			continue
+		}
+		namespace := entries[i].Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// previousNamespace return the latest namespace of the context in the history other than current
+func previousNamespace(file, context, current string) (string, error) {
+	if current == "" {
+		current = "default"
+	}
+	for _, namespace := range recentNamespaces(file, context) {
+		if namespace != current {
+			return namespace, nil
+		}
+	}
+	return "", errors.New("no previous namespace found in the history of context 「" + context + "」")
+}
+
+// recordSwitch record that the context from was left for the context to
+func recordSwitch(file string, config *clientcmdapi.Config, from, to string) error {
+	var records []HistoryEntry
+	for _, name := range []string{from, to} {
+		if ctx, ok := config.Contexts[name]; ok {
+			records = append(records, HistoryEntry{Context: name, Namespace: ctx.Namespace})
+		}
+	}
+	return recordHistory(file, records...)
+}
+
+// sortByRecent move the recently used items to the front, keeping the order of the others
+func sortByRecent[T any](items []T, name func(T) string, recent []string) []T {
+	rank := func(item T) int {
+		if i := slices.Index(recent, name(item)); i >= 0 {
+			return i
+		}
+		return len(recent)
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		return rank(a) - rank(b)
+	})
+	return items
+}
+
+func printHistoryTable(entries []HistoryEntry) error {
+	var table [][]string
+	for _, entry := range entries {
+		table = append(table, []string{entry.Time.Format("2006-01-02 15:04:05"), entry.Context, entry.Namespace})
+	}
+	if table == nil {
+		return errors.New("history not found")
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"TIME", "CONTEXT", "NAMESPACE"})
+	tabulate.SetAlign("center")
+	fmt.Println(tabulate.Render("grid", "left"))
+	return nil
+}
+
+func selectHistory(entries []HistoryEntry, label string) int {
+	templates := &promptui.SelectTemplates{
+		Label:    "{{ . }}",
+		Active:   "\U0001F63C {{ .Context | red }}",
+		Inactive: "  {{ .Context | cyan }}",
+		Selected: "\U0001F638 Select:{{ .Context | green }}",
+		Details: `
+--------- Info ----------
+{{ "Context:" | faint }}	{{ .Context }}
+{{ "Namespace:" | faint }}	{{ .Namespace }}
+{{ "Time:" | faint }}	{{ .Time.Format "2006-01-02 15:04:05" }}`,
+	}
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     entries,
+		Templates: templates,
+		Size:      uiSize,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		log.Fatalf("Prompt failed %v\n", err)
+	}
+	return i
+}
+
+func historyExample() string {
+	return `
+# Select a recently used context and switch to it
+kubecm history
+# List the recently used contexts
+kubecm history --list
+# Switch back to the previous context
+kubecm switch -
+# Switch back to the previous namespace of the current context
+kubecm ns -
+`
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_previousContext(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := "history-config"
+	config := appendMergeConfig.DeepCopy()
+
+	if _, err := previousContext(file, "root-context"); err == nil {
+		t.Errorf("previousContext() want error without history")
+	}
+	if err := recordSwitch(file, config, "root-context", "federal-context"); err != nil {
+		t.Fatalf("recordSwitch() error = %v", err)
+	}
+	got, err := previousContext(file, "federal-context")
+	if err != nil || got != "root-context" {
+		t.Errorf("previousContext() got = %v, error = %v, want root-context", got, err)
+	}
+	// switching back toggles between the two contexts
+	if err = recordSwitch(file, config, "federal-context", "root-context"); err != nil {
+		t.Fatalf("recordSwitch() error = %v", err)
+	}
+	got, err = previousContext(file, "root-context")
+	if err != nil || got != "federal-context" {
+		t.Errorf("previousContext() got = %v, error = %v, want federal-context", got, err)
+	}
+
+	entries, err := recentEntries(file)
+	if err != nil {
+		t.Fatalf("recentEntries() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Context != "root-context" || entries[0].Namespace != "saw-ns" {
+		t.Errorf("recentEntries() got = %v", entries)
+	}
+}
+
+func Test_recordHistoryLimit(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	for i := 0; i < historyLimit+5; i++ {
+		if err := recordHistory("limit-config", HistoryEntry{Context: "root-context"}); err != nil {
+			t.Fatalf("recordHistory() error = %v", err)
+		}
+	}
+	entries, err := loadHistory("limit-config")
+	if err != nil || len(entries) != historyLimit {
+		t.Errorf("loadHistory() got %d entries, error = %v", len(entries), err)
+	}
+}
+
+func Test_sortByRecent(t *testing.T) {
+	kubeItems := []Needle{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	got := sortByRecent(kubeItems, func(n Needle) string { return n.Name }, []string{"c", "a", "x"})
+	want := []Needle{{Name: "c"}, {Name: "a"}, {Name: "b"}, {Name: "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortByRecent() got = %v, want %v", got, want)
+	}
+}
+
+func Test_previousNamespace(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := "namespace-config"
+	err := recordHistory(file,
+		HistoryEntry{Context: "root-context", Namespace: ""},
+		HistoryEntry{Context: "root-context", Namespace: "kube-system"},
+		HistoryEntry{Context: "federal-context", Namespace: "hammer-ns"})
+	if err != nil {
+		t.Fatalf("recordHistory() error = %v", err)
+	}
+	if got := recentNamespaces(file, "root-context"); !reflect.DeepEqual(got, []string{"kube-system", "default"}) {
+		t.Errorf("recentNamespaces() got = %v", got)
+	}
+	got, err := previousNamespace(file, "root-context", "kube-system")
+	if err != nil || got != "default" {
+		t.Errorf("previousNamespace() got = %v, error = %v, want default", got, err)
+	}
+	got, err = previousNamespace(file, "root-context", "")
+	if err != nil || got != "kube-system" {
+		t.Errorf("previousNamespace() got = %v, error = %v, want kube-system", got, err)
+	}
+	if _, err = previousNamespace(file, "federal-context", "hammer-ns"); err == nil {
+		t.Errorf("previousNamespace() want error")
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bndr/gotabulate"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// InfoCommand info command struct
+type InfoCommand struct {
+	BaseCommand
+}
+
+// ContextOrigin record of kubecm info
+type ContextOrigin struct {
+	Name        string            `json:"name"`
+	Cluster     string            `json:"cluster"`
+	Server      string            `json:"server,omitempty"`
+	User        string            `json:"user"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Source      *ContextSource    `json:"source,omitempty"`
+	// SourceStatus is unchanged, changed or missing for the contexts imported from a file
+	SourceStatus string `json:"sourceStatus,omitempty"`
+}
+
+// status of the source file of a context
+const (
+	sourceUnchanged = "unchanged"
+	sourceChanged   = "changed"
+	sourceMissing   = "missing"
+)
+
+// Init InfoCommand
+func (ic *InfoCommand) Init() {
+	ic.command = &cobra.Command{
+		Use:   "info [context]",
+		Short: "Show where a context came from",
+		Long:  "Show the cluster, user, tags and source of a context: the file it was added or merged from, or the cloud cluster",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ic.runInfo(cmd, args)
+		},
+		Example:           infoExample(),
+		ValidArgsFunction: completeContexts,
+	}
+	ic.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml")
+	ic.AddCommands(&DocsCommand{})
+}
+
+func (ic *InfoCommand) runInfo(cmd *cobra.Command, args []string) error {
+	output, _ := ic.command.Flags().GetString("output")
+	if output != "" && output != OutputJSON && output != OutputYAML {
+		return fmt.Errorf("unsupported output format %q, the available values are: json, yaml", output)
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	var name string
+	if len(args) > 0 {
+		name, err = resolveContext(config, args[0])
+	} else {
+		names := make([]string, 0, len(config.Contexts))
+		for key := range config.Contexts {
+			names = append(names, key)
+		}
+		name, err = selectContext(config, names)
+	}
+	if err != nil {
+		return err
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		return err
+	}
+	origin := contextOrigin(config, metadata, name)
+	if output != "" {
+		return printStructured(os.Stdout, output, origin)
+	}
+	printContextOrigin(os.Stdout, origin)
+	return nil
+}
+
+// contextOrigin return the info and source of the context, the source file is read to tell whether it changed
+func contextOrigin(config *clientcmdapi.Config, metadata map[string]*ContextMetadata, name string) *ContextOrigin {
+	ctx := config.Contexts[name]
+	origin := &ContextOrigin{
+		Name:      name,
+		Cluster:   ctx.Cluster,
+		User:      ctx.AuthInfo,
+		Namespace: ctx.Namespace,
+	}
+	if cluster, ok := config.Clusters[ctx.Cluster]; ok {
+		origin.Server = cluster.Server
+	}
+	meta, ok := metadata[name]
+	if !ok {
+		return origin
+	}
+	origin.Tags = meta.Tags
+	origin.Description = meta.Description
+	origin.Source = meta.Source
+	if source := meta.Source; source != nil && source.Path != "" && source.Path != "-" {
+		content, err := os.ReadFile(source.Path)
+		switch {
+		case err != nil:
+			origin.SourceStatus = sourceMissing
+		case contentHash(content) != source.Hash:
+			origin.SourceStatus = sourceChanged
+		default:
+			origin.SourceStatus = sourceUnchanged
+		}
+	}
+	return origin
+}
+
+func printContextOrigin(out io.Writer, origin *ContextOrigin) {
+	rows := [][]string{
+		{"Context", origin.Name},
+		{"Cluster", origin.Cluster},
+		{"Server", origin.Server},
+		{"User", origin.User},
+		{"Namespace", origin.Namespace},
+		{"Tags", formatTags(origin.Tags)},
+		{"Description", origin.Description},
+	}
+	source := origin.Source
+	switch {
+	case source == nil:
+		rows = append(rows, []string{"Source", "unknown"})
+	case source.Path == "-":
+		rows = append(rows, []string{"Source", "stdin"}, []string{"Hash", source.Hash})
+	case source.Path != "":
+		rows = append(rows, []string{"Source", fmt.Sprintf("%s (%s)", source.Path, origin.SourceStatus)}, []string{"Hash", source.Hash})
+	default:
+		rows = append(rows,
+			[]string{"Provider", source.Provider},
+			[]string{"Account", source.Account},
+			[]string{"Region", source.RegionID},
+			[]string{"Cluster ID", source.ClusterID},
+			[]string{"Profile", source.Profile},
+			[]string{"Role", source.RoleARN})
+	}
+	if source != nil && !source.ImportedAt.IsZero() {
+		rows = append(rows, []string{"Imported", source.ImportedAt.Local().Format(time.RFC3339)})
+	}
+	var table [][]string
+	for _, row := range rows {
+		if row[1] != "" {
+			table = append(table, row)
+		}
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"FIELD", "VALUE"})
+	tabulate.SetAlign("left")
+	fmt.Fprintln(out, tabulate.Render("grid", "left"))
+}
+
+func infoExample() string {
+	return `
+# Show where a context came from
+kubecm info my-context
+# Select the context interactively
+kubecm info
+# Output the info as json
+kubecm info my-context -o json
+`
+}
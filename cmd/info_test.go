@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_contextOrigin(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	dir := t.TempDir()
+	cfgFile = filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "admin.conf")
+	newConfig := &clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"admin": {Server: "https://admin.example.org"}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"admin": {Token: "admin-token"}},
+		Contexts:       map[string]*clientcmdapi.Context{"admin": {AuthInfo: "admin", Cluster: "admin"}},
+		CurrentContext: "admin",
+	}
+	if err := clientcmd.WriteToFile(*newConfig, source); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := &AddCommand{}
+	ac.Init()
+	ac.command.SetArgs([]string{"-f", source, "--cover"})
+	if err := ac.command.Execute(); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := contextOrigin(config, metadata, "admin")
+	if origin.Source == nil || origin.Source.Path != source || !strings.HasPrefix(origin.Source.Hash, "sha256:") ||
+		origin.Source.ImportedAt.IsZero() || origin.SourceStatus != sourceUnchanged {
+		t.Fatalf("contextOrigin() got %+v, source %+v", origin, origin.Source)
+	}
+	if origin.Server != "https://admin.example.org" {
+		t.Errorf("contextOrigin() got server %s", origin.Server)
+	}
+	if origin = contextOrigin(config, metadata, "root-context"); origin.Source != nil || origin.Cluster != "pig-cluster" {
+		t.Errorf("contextOrigin() of a context without source got %+v", origin)
+	}
+
+	if err = os.WriteFile(source, []byte("changed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if origin = contextOrigin(config, metadata, "admin"); origin.SourceStatus != sourceChanged {
+		t.Errorf("contextOrigin() got status %s, want %s", origin.SourceStatus, sourceChanged)
+	}
+	if err = os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+	if origin = contextOrigin(config, metadata, "admin"); origin.SourceStatus != sourceMissing {
+		t.Errorf("contextOrigin() got status %s, want %s", origin.SourceStatus, sourceMissing)
+	}
+
+	var out bytes.Buffer
+	printContextOrigin(&out, origin)
+	for _, want := range []string{"admin.conf (missing)", "sha256:", "Imported"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printContextOrigin() got %s, want %s", out.String(), want)
+		}
+	}
+	out.Reset()
+	printContextOrigin(&out, &ContextOrigin{Name: "aws", Source: &ContextSource{Provider: "aws", Account: "1234", RegionID: "us-east-1", ClusterID: "us-east-1/web",
+		Profile: "prod", RoleARN: "arn:aws:iam::1234:role/kubecm"}})
+	for _, want := range []string{"Provider", "1234", "us-east-1/web", "prod", "role/kubecm"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printContextOrigin() got %s, want %s", out.String(), want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -29,41 +30,111 @@ func (lc *ListCommand) Init() {
 		Example: listExample(),
 	}
 	lc.command.DisableFlagsInUseLine = true
+	lc.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml, wide, name")
+	lc.command.Flags().Bool("check", false, "probe the cluster of every context and show its status, version and latency")
+	lc.command.Flags().Duration("check-timeout", 3*time.Second, "timeout of probing each context")
+	lc.command.Flags().Int("check-workers", 8, "number of contexts probed at the same time")
+	lc.command.Flags().Duration("cache-ttl", 5*time.Minute, "show the cached cluster status younger than this, older ones are refreshed in the background, 0 disables the cache")
+	lc.command.Flags().Bool("summary", true, "show the number of namespaces, nodes and pods of the current cluster")
+	lc.command.Flags().Bool("refresh", false, "ignore the cached cluster status and fetch it again")
+	lc.command.Flags().Bool("refresh-cache", false, "only refresh the cached cluster status of the current context, used by the background refresh")
+	_ = lc.command.Flags().MarkHidden("refresh-cache")
 	lc.AddCommands(&DocsCommand{})
 }
 
 func (lc *ListCommand) runList(command *cobra.Command, args []string) error {
-	clusterMessageChan := make(chan *ClusterStatusCheck)
-	go func() {
-		info, _ := ClusterStatus(2)
-		clusterMessageChan <- info
-	}()
+	output, _ := lc.command.Flags().GetString("output")
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+	check, _ := lc.command.Flags().GetBool("check")
+	ttl, _ := lc.command.Flags().GetDuration("cache-ttl")
+	refresh, _ := lc.command.Flags().GetBool("refresh")
+	withSummary, _ := lc.command.Flags().GetBool("summary")
+	withSummary = withSummary && !moreInfoDisabled()
 	config, err := clientcmd.LoadFromFile(cfgFile)
 	if err != nil {
 		return err
 	}
-	config = CheckValidContext(false, config)
+	if refreshCache, _ := lc.command.Flags().GetBool("refresh-cache"); refreshCache {
+		return refreshClusterCache(cfgFile, config, config.CurrentContext, withSummary)
+	}
+	// machine-readable output only prints the contexts
+	structured := output != "" && output != OutputWide
+	// --check probes every context, the current one included
+	summary := !structured && !check
+	var cached *ClusterCache
+	if summary && ttl > 0 && !refresh {
+		cached = cachedCluster(cfgFile, config, config.CurrentContext, withSummary)
+	}
+	clusterMessageChan := make(chan *ClusterStatusCheck, 1)
+	if summary && cached == nil {
+		go func() {
+			info, _ := ClusterStatus(2)
+			clusterMessageChan <- info
+		}()
+	}
+	if !structured {
+		config = CheckValidContext(false, config)
+	}
+	current := config.DeepCopy()
 	outConfig, err := filterArgs(args, config)
 	if err != nil {
 		return err
 	}
-	err = PrintTable(outConfig)
-	if err != nil {
+	infos := contextInfos(outConfig)
+	if check {
+		timeout, _ := lc.command.Flags().GetDuration("check-timeout")
+		workers, _ := lc.command.Flags().GetInt("check-workers")
+		checkContexts(outConfig, infos, workers, timeout)
+	}
+	err = printContextInfos(os.Stdout, infos, output)
+	if err != nil || structured {
 		return err
 	}
+	printCertExpiryWarning(os.Stdout, current, current.CurrentContext)
+	if !summary {
+		return nil
+	}
+	if cached != nil {
+		printClusterCache(os.Stdout, cached, ttl, time.Now(), withSummary)
+		if cached.stale(ttl, time.Now()) {
+			_ = startCacheRefresh(cfgFile, current.CurrentContext, withSummary)
+		}
+		return nil
+	}
 	clusterMessage := <-clusterMessageChan
 	if clusterMessage != nil {
 		printString(os.Stdout, "Cluster check succeeded!")
 		printString(os.Stdout, "\nKubernetes version ")
 		printYellow(os.Stdout, clusterMessage.Version.GitVersion)
 		printService(os.Stdout, "\nKubernetes master", clusterMessage.Config.Host)
-		if err := MoreInfo(clusterMessage.ClientSet, os.Stdout); err != nil {
-			fmt.Println("(Error reporting can be ignored and does not affect usage.)")
+		var kv map[string]int
+		if withSummary {
+			if kv, err = clusterSummary(clusterMessage.ClientSet); err != nil {
+				fmt.Println("(Error reporting can be ignored and does not affect usage.)")
+			} else {
+				printKV(os.Stdout, "[Summary] ", kv)
+			}
+		}
+		if ttl > 0 && err == nil {
+			_ = storeClusterStatus(cfgFile, current, current.CurrentContext, clusterMessage, kv)
 		}
 	}
 	return nil
 }
 
+// checkContexts probe the contexts of the records and fill in their status
+func checkContexts(config *clientcmdapi.Config, infos []ContextInfo, workers int, timeout time.Duration) {
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	for i, result := range probeContexts(config, names, workers, timeout) {
+		infos[i].Check = &result
+	}
+}
+
 func filterArgs(args []string, config *clientcmdapi.Config) (*clientcmdapi.Config, error) {
 	if len(args) == 0 {
 		return config, nil
@@ -96,6 +167,21 @@ kubecm ls
 kubecm l
 # Filter out keywords(Multi-keyword support)
 kubecm ls kind k3s
+# Output the contexts as json or yaml
+kubecm ls -o json
+# Show the auth type of each context
+kubecm ls -o wide
+# Probe the cluster of every context, 8 at a time with a timeout of 3s each by default
+kubecm ls --check
+kubecm ls --check --check-timeout 5s --check-workers 16
+# Output the context names only, useful for scripts
+kubecm ls -o name | xargs -n1 kubectl get nodes --context
+# Fetch the cluster status again instead of showing the cached one
+kubecm ls --refresh
+# Skip counting the namespaces, nodes and pods of the current cluster
+kubecm ls --summary=false
+# Always fetch the cluster status, like before the cache
+kubecm ls --cache-ttl 0
 # Useful environment variables
 KUBECM_DISABLE_K8S_MORE_INFO: it will disable the k8s more info in the output
 `
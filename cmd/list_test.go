@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -70,3 +72,52 @@ func Test_filterArgs(t *testing.T) {
 		})
 	}
 }
+
+func Test_printContexts(t *testing.T) {
+	config := appendMergeConfig.DeepCopy()
+	config.CurrentContext = "root-context"
+	config.AuthInfos["red-user"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws"}}
+
+	out := new(bytes.Buffer)
+	if err := printContexts(out, config, OutputJSON); err != nil {
+		t.Fatalf("printContexts() error = %v", err)
+	}
+	var infos []ContextInfo
+	if err := json.Unmarshal(out.Bytes(), &infos); err != nil {
+		t.Fatalf("printContexts() json output is invalid: %v", err)
+	}
+	want := []ContextInfo{
+		{Name: "federal-context", Cluster: "cow-cluster", User: "red-user", Server: "http://cow.org:8080", Namespace: "hammer-ns", AuthType: "exec"},
+		{Current: true, Name: "root-context", Cluster: "pig-cluster", User: "black-user", Server: "http://pig.org:8080", Namespace: "saw-ns", AuthType: "token"},
+	}
+	if !reflect.DeepEqual(infos, want) {
+		t.Errorf("printContexts() got = %v, want %v", infos, want)
+	}
+
+	out.Reset()
+	if err := printContexts(out, config, OutputName); err != nil {
+		t.Fatalf("printContexts() error = %v", err)
+	}
+	if out.String() != "federal-context\nroot-context\n" {
+		t.Errorf("printContexts() got = %q", out.String())
+	}
+
+	out.Reset()
+	if err := printContexts(out, config, OutputYAML); err != nil {
+		t.Fatalf("printContexts() error = %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("authType: token")) {
+		t.Errorf("printContexts() yaml output got = %s", out.String())
+	}
+}
+
+func Test_validateOutput(t *testing.T) {
+	for _, output := range []string{"", "json", "yaml", "wide", "name"} {
+		if err := validateOutput(output); err != nil {
+			t.Errorf("validateOutput(%q) error = %v", output, err)
+		}
+	}
+	if err := validateOutput("xml"); err == nil {
+		t.Errorf("validateOutput(%q) want error", "xml")
+	}
+}
@@ -62,6 +62,7 @@ func (mc MergeCommand) runMerge(command *cobra.Command, args []string) error {
 		return fmt.Errorf("please enter the files to be merged")
 	}
 	outConfigs := clientcmdapi.NewConfig()
+	sources := make(map[string]*ContextSource)
 	for _, yaml := range files {
 		printString(os.Stdout, "Loading KubeConfig file: "+yaml+" \n")
 		loadConfig, err := loadKubeConfig(yaml)
@@ -70,14 +71,25 @@ func (mc MergeCommand) runMerge(command *cobra.Command, args []string) error {
 			printWarning(os.Stdout, "File "+yaml+" is not kubeconfig\n")
 			continue
 		}
+		content, err := os.ReadFile(yaml)
+		if err != nil {
+			return err
+		}
 		kco := &KubeConfigOption{
 			config:   loadConfig,
 			fileName: getFileName(yaml),
 		}
-		outConfigs, err = kco.handleContexts(outConfigs, contextPrefix, selectContext, contextTemplate, context)
+		merged, err := kco.handleContexts(outConfigs, contextPrefix, selectContext, contextTemplate, context)
 		if err != nil {
 			return err
 		}
+		kco.source = fileSource(yaml, content)
+		for name := range merged.Contexts {
+			if _, ok := outConfigs.Contexts[name]; !ok {
+				sources[name] = kco.contextSource(name)
+			}
+		}
+		outConfigs = merged
 	}
 
 	if len(outConfigs.Contexts) == 0 {
@@ -85,15 +97,23 @@ func (mc MergeCommand) runMerge(command *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// the kubeconfig the merged one replaces, the contexts changed meanwhile are kept
+	current, err := loadConfigFile(cfgFile)
+	if err != nil {
+		return err
+	}
 	confirm, _ := mc.command.Flags().GetBool("assumeyes")
-	if !confirm {
+	if !confirm && !dryRun {
 		cover := BoolUI(fmt.Sprintf("Are you sure you want to overwrite the 「%s」 file?", cfgFile))
 		confirm, _ = strconv.ParseBool(cover)
 	}
-	err = WriteConfig(confirm, cfgFile, outConfigs)
+	err = WriteConfig(confirm, cfgFile, current, outConfigs)
 	if err != nil {
 		return err
 	}
+	if confirm {
+		recordSources(cfgFile, sources)
+	}
 	return MacNotifier("Merge Successfully")
 }
 
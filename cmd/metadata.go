@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/utils"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// envTag is the tag key of the environment label, e.g. env=prod
+const envTag = "env"
+
+// ContextMetadata what kubecm knows about a context besides the kubeconfig
+type ContextMetadata struct {
+	Tags        map[string]string `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Source      *ContextSource    `json:"source,omitempty"`
+}
+
+// ContextSource where the kubeconfig of a context came from
+type ContextSource struct {
+	// Path is the absolute path of the added or merged kubeconfig file, - for stdin
+	Path string `json:"path,omitempty"`
+	// Hash is the sha256 of the content of the file when it was imported
+	Hash string `json:"hash,omitempty"`
+	// Context is the name of the context in the source kubeconfig
+	Context string `json:"context,omitempty"`
+	// Provider is the first alias of the cloud provider
+	Provider  string `json:"provider,omitempty"`
+	Account   string `json:"account,omitempty"`
+	RegionID  string `json:"regionId,omitempty"`
+	ClusterID string `json:"clusterId,omitempty"`
+	// Profile, RoleARN and ExternalID are the identity the cloud cluster was added with
+	Profile    string    `json:"profile,omitempty"`
+	RoleARN    string    `json:"roleArn,omitempty"`
+	ExternalID string    `json:"externalId,omitempty"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// fileSource return the source of the contexts imported from the kubeconfig file of content
+func fileSource(path string, content []byte) *ContextSource {
+	if path != "-" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return &ContextSource{Path: path, Hash: contentHash(content), ImportedAt: time.Now()}
+}
+
+// contentHash return the sha256 of the content, in the format of ContextSource.Hash
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// empty report whether there is nothing worth keeping
+func (m *ContextMetadata) empty() bool {
+	return len(m.Tags) == 0 && m.Description == "" && m.Source == nil
+}
+
+// metadataFile return the metadata file of the kubeconfig file
+func metadataFile(file string) string {
+	return filepath.Join(kubecmHome(), "metadata", pathHash(file)+".json")
+}
+
+// loadMetadata return the metadata of the contexts in the kubeconfig file, keyed by context name
+func loadMetadata(file string) (map[string]*ContextMetadata, error) {
+	metadata := make(map[string]*ContextMetadata)
+	content, err := os.ReadFile(metadataFile(file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return metadata, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %v", metadataFile(file), err)
+	}
+	return metadata, nil
+}
+
+// updateMetadata apply change to the metadata of the kubeconfig file under lock
+func updateMetadata(file string, change func(metadata map[string]*ContextMetadata) error) error {
+	path := metadataFile(file)
+	unlock, err := utils.LockFile(lockPath(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	metadata, err := loadMetadata(file)
+	if err != nil {
+		return err
+	}
+	if err = change(metadata); err != nil {
+		return err
+	}
+	for name, meta := range metadata {
+		if meta == nil || meta.empty() {
+			delete(metadata, name)
+		}
+	}
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, content, 0600)
+}
+
+// setContextSource set the source of the context in metadata
+func setContextSource(metadata map[string]*ContextMetadata, name string, source *ContextSource) {
+	meta, ok := metadata[name]
+	if !ok || meta == nil {
+		meta = &ContextMetadata{}
+		metadata[name] = meta
+	}
+	meta.Source = source
+}
+
+// recordSources set the source of the contexts of the kubeconfig file, a failure is only a warning
+// since the kubeconfig is already written
+func recordSources(file string, sources map[string]*ContextSource) {
+	if len(sources) == 0 || dryRun {
+		return
+	}
+	err := updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		for name, source := range sources {
+			setContextSource(metadata, name, source)
+		}
+		return nil
+	})
+	if err != nil {
+		printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to record the source of the contexts: %v\n", err))
+	}
+}
+
+// renameMetadata move the metadata of a renamed context
+func renameMetadata(file, oldName, newName string) error {
+	return updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		if meta, ok := metadata[oldName]; ok {
+			metadata[newName] = meta
+			delete(metadata, oldName)
+		}
+		return nil
+	})
+}
+
+// deleteMetadata drop the metadata of deleted contexts
+func deleteMetadata(file string, names ...string) error {
+	return updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		for _, name := range names {
+			delete(metadata, name)
+		}
+		return nil
+	})
+}
+
+// removedContexts return the contexts of oldConfig missing from config
+func removedContexts(oldConfig, config *clientcmdapi.Config) []string {
+	var names []string
+	for name := range oldConfig.Contexts {
+		if _, ok := config.Contexts[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseTags parse key=value arguments, a bare key is a tag without value
+func parseTags(args []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, ", ") {
+			return nil, fmt.Errorf("invalid tag %q, the format is key=value or key", arg)
+		}
+		tags[key] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// formatTags return the tags as sorted key=value pairs joined by commas
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		if value == "" {
+			pairs = append(pairs, key)
+		} else {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// matchTags report whether tags have all the selectors, a selector without value only needs the key
+func matchTags(tags map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		key, value, withValue := strings.Cut(selector, "=")
+		got, ok := tags[key]
+		if !ok || (withValue && got != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterContextsByTags return a copy of config holding only the contexts with all the tag selectors
+func filterContextsByTags(config *clientcmdapi.Config, metadata map[string]*ContextMetadata, selectors []string) (*clientcmdapi.Config, error) {
+	filtered := *config
+	filtered.Contexts = make(map[string]*clientcmdapi.Context)
+	for name, ctx := range config.Contexts {
+		if meta, ok := metadata[name]; ok && matchTags(meta.Tags, selectors) {
+			filtered.Contexts[name] = ctx
+		}
+	}
+	if len(filtered.Contexts) == 0 {
+		return nil, fmt.Errorf("there is no context with tags %s", strings.Join(selectors, ","))
+	}
+	return &filtered, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"key-value", []string{"env=prod", "team=payments"}, map[string]string{"env": "prod", "team": "payments"}, false},
+		{"bare-key", []string{"critical"}, map[string]string{"critical": ""}, false},
+		{"empty-key", []string{"=prod"}, nil, true},
+		{"comma", []string{"a,b=c"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTags() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchTags(t *testing.T) {
+	tags := map[string]string{"env": "prod", "critical": ""}
+	tests := []struct {
+		name      string
+		selectors []string
+		want      bool
+	}{
+		{"value", []string{"env=prod"}, true},
+		{"other-value", []string{"env=dev"}, false},
+		{"key", []string{"critical"}, true},
+		{"all", []string{"env=prod", "critical"}, true},
+		{"missing", []string{"env=prod", "team"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchTags(tags, tt.selectors); got != tt.want {
+				t.Errorf("matchTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := formatTags(tags); got != "critical,env=prod" {
+		t.Errorf("formatTags() = %v", got)
+	}
+}
+
+func Test_metadataSync(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := "metadata-config"
+	err := updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		metadata["root-context"] = &ContextMetadata{Tags: map[string]string{"env": "prod"}, Description: "root"}
+		metadata["federal-context"] = &ContextMetadata{Tags: map[string]string{"env": "dev"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateMetadata() error = %v", err)
+	}
+	if err = renameMetadata(file, "root-context", "new-context"); err != nil {
+		t.Fatalf("renameMetadata() error = %v", err)
+	}
+	if err = deleteMetadata(file, "federal-context"); err != nil {
+		t.Fatalf("deleteMetadata() error = %v", err)
+	}
+	metadata, err := loadMetadata(file)
+	if err != nil {
+		t.Fatalf("loadMetadata() error = %v", err)
+	}
+	want := map[string]*ContextMetadata{
+		"new-context": {Tags: map[string]string{"env": "prod"}, Description: "root"},
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("loadMetadata() got = %v, want %v", metadata, want)
+	}
+
+	config := appendMergeConfig.DeepCopy()
+	config.Contexts["new-context"] = config.Contexts["root-context"]
+	filtered, err := filterContextsByTags(config, metadata, []string{"env=prod"})
+	if err != nil {
+		t.Fatalf("filterContextsByTags() error = %v", err)
+	}
+	if len(filtered.Contexts) != 1 || filtered.Contexts["new-context"] == nil || len(config.Contexts) != 3 {
+		t.Errorf("filterContextsByTags() got = %v", filtered.Contexts)
+	}
+	if _, err = filterContextsByTags(config, metadata, []string{"env=dev"}); err == nil {
+		t.Errorf("filterContextsByTags() want error without matching contexts")
+	}
+}
@@ -9,10 +9,12 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 // NamespaceCommand namespace cmd struct
@@ -35,6 +37,7 @@ Switch or change namespace interactively
 		},
 		Example: namespaceExample(),
 	}
+	nc.command.Flags().BoolP("yes", "y", false, "change the namespace of a protected context without confirmation")
 	nc.AddCommands(&DocsCommand{})
 }
 
@@ -49,37 +52,68 @@ func (nc *NamespaceCommand) runNamespace(command *cobra.Command, args []string)
 
 	currentContext := config.CurrentContext
 	currentNamespace := config.Contexts[currentContext].Namespace
+	fromNamespace := currentNamespace
 	clientset, err := GetClientSet(cfgFile)
 	if err != nil {
 		return err
 	}
 
+	if len(args) == 1 && args[0] == "-" {
+		previous, err := previousNamespace(cfgFile, currentContext, currentNamespace)
+		if err != nil {
+			return err
+		}
+		args = []string{previous}
+	}
 	if len(args) == 0 {
 		namespaceList, err := GetNamespaceList(currentNamespace, clientset)
 		if err != nil {
 			return err
 		}
+		namespaceList = sortByRecent(namespaceList, func(n Namespaces) string { return n.Name }, recentNamespaces(cfgFile, currentContext))
 		// exit option
 		namespaceList = append(namespaceList, Namespaces{Name: "<Exit>", Default: false})
 		num := selectNamespace(namespaceList)
-		config.Contexts[currentContext].Namespace = namespaceList[num].Name
+		currentNamespace = namespaceList[num].Name
 	} else {
 		exist, err := CheckNamespaceExist(args[0], clientset)
 		if err != nil {
 			return errors.New("Can not find namespace: " + args[0])
 		}
 		if exist {
-			config.Contexts[currentContext].Namespace = args[0]
+			currentNamespace = args[0]
 			fmt.Printf("Namespace: 「%s」 is selected.\n", args[0])
 		} else {
 			return errors.New("Can not find namespace: " + args[0])
 		}
 	}
-	err = WriteConfig(true, cfgFile, config)
-	if err != nil {
+	yes, _ := nc.command.Flags().GetBool("yes")
+	if err = confirmProtected(currentContext, yes); err != nil {
+		return err
+	}
+	err = ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+		return setNamespace(config, currentContext, currentNamespace)
+	})
+	if err != nil || dryRun {
 		return err
 	}
-	return MacNotifier(fmt.Sprintf("Switch to the [%s] namespace\n", config.Contexts[currentContext].Namespace))
+	err = recordHistory(cfgFile,
+		HistoryEntry{Context: currentContext, Namespace: fromNamespace},
+		HistoryEntry{Context: currentContext, Namespace: currentNamespace})
+	if err != nil {
+		printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to record the switch history: %v\n", err))
+	}
+	return MacNotifier(fmt.Sprintf("Switch to the [%s] namespace\n", currentNamespace))
+}
+
+// setNamespace set the namespace of the context
+func setNamespace(config *clientcmdapi.Config, context, namespace string) error {
+	ctx, ok := config.Contexts[context]
+	if !ok {
+		return errors.New("cannot find context named 「" + context + "」")
+	}
+	ctx.Namespace = namespace
+	return nil
 }
 
 func selectNamespace(namespaces []Namespaces) int {
@@ -138,6 +172,16 @@ func GetClientSet(configFile string) (kubernetes.Interface, error) {
 	return kubernetes.NewForConfig(config)
 }
 
+// GetClientSetForContext return clientset of the context in config, rather than the current context
+func GetClientSetForContext(config *clientcmdapi.Config, context string) (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, context, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = 5 * time.Second
+	return kubernetes.NewForConfig(restConfig)
+}
+
 // GetNamespaceList return namespace list
 func GetNamespaceList(currentNamespace string, clientset kubernetes.Interface) ([]Namespaces, error) {
 	var nss []Namespaces
@@ -184,5 +228,9 @@ kubecm namespace
 kubecm ns
 # change to namespace of kube-system
 kubecm ns kube-system
+# change back to the previous namespace of the current context
+kubecm ns -
+# change the namespace of a protected context without typing its name
+kubecm ns kube-system --yes
 `
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+)
+
+// output formats of the -o flag
+const (
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+	OutputWide = "wide"
+	OutputName = "name"
+)
+
+// validateOutput check the value of the -o flag, empty means the default table
+func validateOutput(output string) error {
+	switch output {
+	case "", OutputJSON, OutputYAML, OutputWide, OutputName:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, the available values are: json, yaml, wide, name", output)
+}
+
+// printStructured print items as json or yaml
+func printStructured(out io.Writer, output string, items interface{}) error {
+	var content []byte
+	var err error
+	switch output {
+	case OutputJSON:
+		content, err = json.MarshalIndent(items, "", "  ")
+		content = append(content, '\n')
+	case OutputYAML:
+		content, err = yaml.Marshal(items)
+	default:
+		return fmt.Errorf("output format %q is not json or yaml", output)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(content)
+	return err
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// statuses of a context probed by kubecm list --check
+const (
+	ProbeOK           = "ok"
+	ProbeUnreachable  = "unreachable"
+	ProbeUnauthorized = "unauthorized"
+	ProbeTLSError     = "tls-error"
+	ProbeTimeout      = "timeout"
+	ProbeError        = "error"
+)
+
+// ProbeResult health of a context
+type ProbeResult struct {
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// contextClusterStatus check the cluster of the context in config, rather than the current context of cfgFile
+func contextClusterStatus(config *clientcmdapi.Config, name string, timeout time.Duration) (*ClusterStatusCheck, error) {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, name, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = timeout
+	clientSet, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	serverVersion, err := clientSet.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return &ClusterStatusCheck{
+		Version:   serverVersion,
+		ClientSet: clientSet,
+		Config:    restConfig,
+	}, nil
+}
+
+// probeContext request the version of the cluster of the context and classify the failure
+func probeContext(config *clientcmdapi.Config, name string, timeout time.Duration) ProbeResult {
+	start := time.Now()
+	status, err := contextClusterStatus(config, name, timeout)
+	latency := time.Since(start).Round(time.Millisecond).String()
+	if err != nil {
+		return ProbeResult{Status: probeStatus(err), Latency: latency, Error: err.Error()}
+	}
+	return ProbeResult{Status: ProbeOK, Version: status.Version.GitVersion, Latency: latency}
+}
+
+// probeStatus classify the error of a probe
+func probeStatus(err error) string {
+	var (
+		netErr          net.Error
+		unknownAuth     x509.UnknownAuthorityError
+		hostnameErr     x509.HostnameError
+		certInvalid     x509.CertificateInvalidError
+		verificationErr *tls.CertificateVerificationError
+		recordErr       tls.RecordHeaderError
+		opErr           *net.OpError
+	)
+	switch {
+	case apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err):
+		return ProbeUnauthorized
+	case errors.As(err, &unknownAuth) || errors.As(err, &hostnameErr) || errors.As(err, &certInvalid) ||
+		errors.As(err, &verificationErr) || errors.As(err, &recordErr):
+		return ProbeTLSError
+	case errors.Is(err, context.DeadlineExceeded) || apierrors.IsTimeout(err) ||
+		(errors.As(err, &netErr) && netErr.Timeout()):
+		return ProbeTimeout
+	case errors.As(err, &opErr):
+		return ProbeUnreachable
+	}
+	return ProbeError
+}
+
+// probeContexts probe the contexts concurrently with at most workers probes at a time,
+// the results are in the order of names
+func probeContexts(config *clientcmdapi.Config, names []string, workers int, timeout time.Duration) []ProbeResult {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]ProbeResult, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = probeContext(config, names[i], timeout)
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
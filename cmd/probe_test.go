@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func Test_probeContexts(t *testing.T) {
+	version := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"major":"1","minor":"30","gitVersion":"v1.30.1"}`))
+	})
+	ok := httptest.NewServer(version)
+	defer ok.Close()
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+	}))
+	defer unauthorized.Close()
+	untrusted := httptest.NewTLSServer(version)
+	defer untrusted.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+		version(w, r)
+	}))
+	defer slow.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + listener.Addr().String()
+	listener.Close()
+
+	servers := map[string]string{
+		"ok":           ok.URL,
+		"unauthorized": unauthorized.URL,
+		"untrusted":    untrusted.URL,
+		"slow":         slow.URL,
+		"closed":       closed,
+	}
+	config := clientcmdapi.NewConfig()
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	var names []string
+	for name, server := range servers {
+		config.Clusters[name] = &clientcmdapi.Cluster{Server: server}
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: "user"}
+		names = append(names, name)
+	}
+	want := map[string]string{
+		"ok":           ProbeOK,
+		"unauthorized": ProbeUnauthorized,
+		"untrusted":    ProbeTLSError,
+		"slow":         ProbeTimeout,
+		"closed":       ProbeUnreachable,
+	}
+	results := probeContexts(config, names, 2, 300*time.Millisecond)
+	for i, name := range names {
+		if results[i].Status != want[name] {
+			t.Errorf("probeContexts() %s got = %+v, want status %s", name, results[i], want[name])
+		}
+	}
+	if results[0].Latency == "" {
+		t.Errorf("probeContexts() want latency, got %+v", results[0])
+	}
+	for i, name := range names {
+		if name == "ok" && results[i].Version != "v1.30.1" {
+			t.Errorf("probeContexts() version got = %v, want v1.30.1", results[i].Version)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	// protectedEnv holds comma separated name patterns of the protected contexts, e.g. *prod*,live-?
+	protectedEnv = "KUBECM_PROTECTED_CONTEXTS"
+	// protectedTag marks a context as protected, see kubecm tag
+	protectedTag = "protected"
+	// ExitProtected is the exit code when switching to a protected context is not confirmed
+	ExitProtected = 3
+)
+
+// ExitError an error with the exit code of kubecm
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode return the exit code of kubecm for the error returned by the command
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// isProtected report whether the context matches a pattern of KUBECM_PROTECTED_CONTEXTS or has the protected tag
+func isProtected(name string) bool {
+	for _, pattern := range strings.Split(os.Getenv(protectedEnv), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matchProtected(pattern, name) {
+			return true
+		}
+	}
+	// the tags of the contexts of kubecm shell are the ones of the kubeconfig they were exported from
+	metadata, err := loadMetadata(originConfig())
+	if err != nil {
+		// fail closed, an unreadable metadata file must not bypass the confirmation
+		return true
+	}
+	if meta, ok := metadata[name]; ok {
+		value, tagged := meta.Tags[protectedTag]
+		return tagged && value != "false"
+	}
+	return false
+}
+
+// matchProtected report whether the name matches the pattern of path.Match, whose * and ? also match
+// the / of the cloud contexts, e.g. REGION/NAME or the ARN of an EKS cluster
+func matchProtected(pattern, name string) bool {
+	// path.Match does not match / with a wildcard, it is replaced by a character kept in both
+	const slash = "\x00"
+	ok, _ := path.Match(strings.ReplaceAll(pattern, "/", slash), strings.ReplaceAll(name, "/", slash))
+	return ok
+}
+
+// confirmProtected print a banner and ask to type the context name when the context is protected,
+// yes skips the confirmation
+func confirmProtected(name string, yes bool) error {
+	if dryRun || !isProtected(name) {
+		return nil
+	}
+	printProtectedBanner(os.Stdout, name)
+	if yes {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return &ExitError{Code: ExitProtected, Err: fmt.Errorf("context 「%s」 is protected, use --yes to confirm without a terminal", name)}
+	}
+	prompt := promptui.Prompt{
+		Label: fmt.Sprintf("Type 「%s」 to confirm", name),
+	}
+	return confirmProtectedWithRunner(name, &prompt)
+}
+
+func confirmProtectedWithRunner(name string, runner PromptRunner) error {
+	input, err := runner.Run()
+	if err != nil || strings.TrimSpace(input) != name {
+		return &ExitError{Code: ExitProtected, Err: fmt.Errorf("context 「%s」 is protected and was not confirmed, nothing changed", name)}
+	}
+	return nil
+}
+
+func printProtectedBanner(out io.Writer, name string) {
+	line := strings.Repeat("!", 60)
+	printWarning(out, fmt.Sprintf("%s\n!!  WARNING: 「%s」 is a PROTECTED context\n%s\n", line, name, line))
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func Test_isProtected(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv(protectedEnv, "*prod*, live-?")
+	err := updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+		metadata["payments"] = &ContextMetadata{Tags: map[string]string{protectedTag: ""}}
+		metadata["sandbox"] = &ContextMetadata{Tags: map[string]string{protectedTag: "false"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateMetadata() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"eu-prod-1", true},
+		{"us-east-1/prod-cluster", true},
+		{"arn:aws:eks:us-east-1:123456789012:cluster/prod", true},
+		{"live-1", true},
+		{"live-10", false},
+		{"payments", true},
+		{"sandbox", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProtected(tt.name); got != tt.want {
+				t.Errorf("isProtected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isProtected_shell(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv(protectedEnv, "")
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	origin := filepath.Join(t.TempDir(), "config")
+	err := updateMetadata(origin, func(metadata map[string]*ContextMetadata) error {
+		metadata["payments"] = &ContextMetadata{Tags: map[string]string{protectedTag: ""}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("updateMetadata() error = %v", err)
+	}
+	// inside kubecm shell the kubeconfig is the private one exported from origin
+	cfgFile = filepath.Join(t.TempDir(), "kubecm-shell.yaml")
+	t.Setenv("KUBECONFIG", cfgFile)
+	t.Setenv(shellEnv, "payments")
+	t.Setenv(shellOriginEnv, origin)
+	if !isProtected("payments") {
+		t.Errorf("isProtected() inside kubecm shell ignored the tag of the origin kubeconfig")
+	}
+	if originConfig() != origin {
+		t.Errorf("originConfig() = %s, want %s", originConfig(), origin)
+	}
+}
+
+func Test_confirmProtected(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv(protectedEnv, "prod")
+	defer func(f func() bool) { stdinIsTerminal = f }(stdinIsTerminal)
+	stdinIsTerminal = func() bool { return false }
+
+	if err := confirmProtected("dev", false); err != nil {
+		t.Errorf("confirmProtected() error = %v for an unprotected context", err)
+	}
+	if err := confirmProtected("prod", true); err != nil {
+		t.Errorf("confirmProtected() error = %v with yes", err)
+	}
+	err := confirmProtected("prod", false)
+	if ExitCode(err) != ExitProtected {
+		t.Errorf("confirmProtected() error = %v, want exit code %d without a terminal", err, ExitProtected)
+	}
+
+	if err = confirmProtectedWithRunner("prod", &testStringPrompt{result: "prod"}); err != nil {
+		t.Errorf("confirmProtectedWithRunner() error = %v", err)
+	}
+	err = confirmProtectedWithRunner("prod", &testStringPrompt{result: "dev"})
+	if ExitCode(err) != ExitProtected {
+		t.Errorf("confirmProtectedWithRunner() error = %v, want exit code %d", err, ExitProtected)
+	}
+	err = confirmProtectedWithRunner("prod", &testStringPrompt{err: errors.New("^C")})
+	if ExitCode(err) != ExitProtected {
+		t.Errorf("confirmProtectedWithRunner() error = %v, want exit code %d", err, ExitProtected)
+	}
+	if ExitCode(errors.New("other")) != 1 {
+		t.Errorf("ExitCode() want 1 for other errors")
+	}
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BussanQ/kubecm/pkg/cloud"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RefreshCommand refresh command struct
+type RefreshCommand struct {
+	BaseCommand
+}
+
+// refreshedContext the kubeconfig fetched again for a context
+type refreshedContext struct {
+	name    string
+	config  *clientcmdapi.Config
+	context *clientcmdapi.Context
+	source  *ContextSource
+}
+
+// Init RefreshCommand
+func (rc *RefreshCommand) Init() {
+	rc.command = &cobra.Command{
+		Use:   "refresh [context...]",
+		Short: "Fetch the credentials of contexts again from their source",
+		Long:  "Fetch the kubeconfig of contexts again from the cloud cluster or the file they were added from, and replace their user and cluster, keeping the name and namespace of the contexts",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rc.runRefresh(cmd, args)
+		},
+		Example:           refreshExample(),
+		ValidArgsFunction: completeContexts,
+	}
+	rc.AddCommands(&DocsCommand{})
+}
+
+func (rc *RefreshCommand) runRefresh(cmd *cobra.Command, args []string) error {
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if len(args) == 0 {
+		var sourced []string
+		for name, meta := range metadata {
+			if _, ok := config.Contexts[name]; ok && meta.Source != nil {
+				sourced = append(sourced, name)
+			}
+		}
+		if len(sourced) == 0 {
+			return errors.New("no context has a recorded source, add them with kubecm add, merge or cloud add")
+		}
+		name, err := selectContext(config, sourced)
+		if err != nil {
+			return err
+		}
+		names = []string{name}
+	}
+	for _, arg := range args {
+		name, err := resolveContext(config, arg)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+
+	var prompter cloud.Prompter
+	if stdinIsTerminal() {
+		prompter = promptPrompter{}
+	}
+	var (
+		refreshed []refreshedContext
+		failed    []string
+	)
+	for _, name := range names {
+		item, err := fetchContext(name, metadata[name], prompter)
+		if err != nil {
+			if len(names) == 1 {
+				return err
+			}
+			printWarning(os.Stderr, fmt.Sprintf("failed to refresh 「%s」: %v\n", name, err))
+			failed = append(failed, name)
+			continue
+		}
+		refreshed = append(refreshed, item)
+	}
+	if len(refreshed) > 0 {
+		err = ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+			for _, item := range refreshed {
+				if err := replaceContextCredentials(config, item); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sources := make(map[string]*ContextSource)
+		for _, item := range refreshed {
+			sources[item.name] = item.source
+		}
+		recordSources(cfgFile, sources)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to refresh %d of %d contexts: %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// fetchContext fetch the kubeconfig of the context again from the cloud cluster or file of its source
+func fetchContext(name string, meta *ContextMetadata, prompter cloud.Prompter) (refreshedContext, error) {
+	item := refreshedContext{name: name}
+	if meta == nil || meta.Source == nil {
+		return item, fmt.Errorf("「%s」 has no recorded source, add it again with kubecm add or kubecm cloud add", name)
+	}
+	source := *meta.Source
+	var err error
+	switch {
+	case source.Provider != "":
+		provider := cloud.LookupProvider(source.Provider)
+		if provider == nil {
+			return item, fmt.Errorf("the provider %s of 「%s」 is not supported", source.Provider, name)
+		}
+		var client cloud.Cluster
+		client, err = newCloudClient(provider, &cloud.Session{
+			RegionID:   source.RegionID,
+			Prompter:   prompter,
+			Profile:    source.Profile,
+			RoleARN:    source.RoleARN,
+			ExternalID: source.ExternalID,
+		})
+		if err != nil {
+			return item, err
+		}
+		if item.config, err = cloud.GetKubeConfigObj(client, source.ClusterID); err != nil {
+			return item, err
+		}
+	case source.Path == "-":
+		return item, fmt.Errorf("「%s」 was added from stdin, add it again to refresh it", name)
+	case source.Path != "":
+		content, err := os.ReadFile(source.Path)
+		if err != nil {
+			return item, err
+		}
+		if item.config, err = clientcmd.LoadFromFile(source.Path); err != nil {
+			return item, err
+		}
+		source.Hash = contentHash(content)
+	default:
+		return item, fmt.Errorf("「%s」 has no recorded source, add it again with kubecm add or kubecm cloud add", name)
+	}
+	contextName := source.Context
+	if _, ok := item.config.Contexts[contextName]; !ok {
+		contextName = item.config.CurrentContext
+	}
+	if _, ok := item.config.Contexts[contextName]; !ok && len(item.config.Contexts) == 1 {
+		for key := range item.config.Contexts {
+			contextName = key
+		}
+	}
+	item.context = item.config.Contexts[contextName]
+	if item.context == nil {
+		return item, fmt.Errorf("cannot find the context of 「%s」 in its source", name)
+	}
+	source.Context = contextName
+	source.ImportedAt = time.Now()
+	item.source = &source
+	return item, nil
+}
+
+// replaceContextCredentials replace the cluster and user of the context with the refreshed ones, the name
+// and namespace of the context stay the same
+func replaceContextCredentials(config *clientcmdapi.Config, item refreshedContext) error {
+	ctx, ok := config.Contexts[item.name]
+	if !ok {
+		return errors.New("cannot find context named 「" + item.name + "」")
+	}
+	cluster, ok := item.config.Clusters[item.context.Cluster]
+	if !ok {
+		return fmt.Errorf("the cluster %s of 「%s」 is missing from its source", item.context.Cluster, item.name)
+	}
+	user, ok := item.config.AuthInfos[item.context.AuthInfo]
+	if !ok {
+		return fmt.Errorf("the user %s of 「%s」 is missing from its source", item.context.AuthInfo, item.name)
+	}
+	cluster = cluster.DeepCopy()
+	// keep the skipped verification of kubecm add --insecure-skip-tls-verify
+	if old, ok := config.Clusters[ctx.Cluster]; ok && old.InsecureSkipTLSVerify {
+		cluster.InsecureSkipTLSVerify = true
+		cluster.CertificateAuthority = ""
+		cluster.CertificateAuthorityData = nil
+	}
+	config.Clusters[ctx.Cluster] = cluster
+	config.AuthInfos[ctx.AuthInfo] = user.DeepCopy()
+	fmt.Printf("Refresh Context: %s \n", item.name)
+	return nil
+}
+
+func refreshExample() string {
+	return `
+# Fetch the credentials of a context again from the cloud cluster or file it was added from
+kubecm refresh my-context
+# Refresh several contexts
+kubecm refresh my-context1 my-context2
+# Select the context to refresh interactively
+kubecm refresh
+`
+}
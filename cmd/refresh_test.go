@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// runRefreshCommand run kubecm refresh with the args and return the kubeconfig it wrote
+func runRefreshCommand(t *testing.T, args ...string) (*clientcmdapi.Config, error) {
+	t.Helper()
+	rc := &RefreshCommand{}
+	rc.Init()
+	rc.command.SetArgs(args)
+	rc.command.SilenceUsage = true
+	rc.command.SilenceErrors = true
+	err := rc.command.Execute()
+	config, loadErr := clientcmd.LoadFromFile(cfgFile)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	return config, err
+}
+
+func Test_runRefresh(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv("TEST_CLOUD_KEY", "key")
+	registerTestProvider(t, "one")
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	dir := t.TempDir()
+	cfgFile = filepath.Join(dir, "config")
+	if err := clientcmd.WriteToFile(*appendMergeConfig.DeepCopy(), cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "admin.conf")
+	adminConfig := func(server, token string) {
+		err := clientcmd.WriteToFile(clientcmdapi.Config{
+			Clusters:       map[string]*clientcmdapi.Cluster{"admin": {Server: server}},
+			AuthInfos:      map[string]*clientcmdapi.AuthInfo{"admin": {Token: token}},
+			Contexts:       map[string]*clientcmdapi.Context{"admin": {AuthInfo: "admin", Cluster: "admin"}},
+			CurrentContext: "admin",
+		}, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	adminConfig("https://admin.example.org", "old-token")
+	ac := &AddCommand{}
+	ac.Init()
+	ac.command.SetArgs([]string{"-f", source, "--cover"})
+	if err := ac.command.Execute(); err != nil {
+		t.Fatalf("runAdd() error = %v", err)
+	}
+	if _, err := runCloudCommand(t, "add", "--non-interactive", "--provider", "test", "--region_id", "r", "--all", "--cover"); err != nil {
+		t.Fatalf("runCloudAdd() error = %v", err)
+	}
+
+	// the contexts are renamed and moved to another namespace, the token of one expired
+	err := UpdateConfigFile(cfgFile, func(config *clientcmdapi.Config) error {
+		config.Contexts["prod"] = config.Contexts["admin"]
+		config.Contexts["prod"].Namespace = "payments"
+		delete(config.Contexts, "admin")
+		config.AuthInfos[config.Contexts["one"].AuthInfo].Token = "expired"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = renameMetadata(cfgFile, "admin", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	adminConfig("https://new.example.org", "new-token")
+
+	config, err := runRefreshCommand(t, "prod", "one")
+	if err != nil {
+		t.Fatalf("runRefresh() error = %v", err)
+	}
+	prod := config.Contexts["prod"]
+	if prod == nil || prod.Namespace != "payments" {
+		t.Fatalf("runRefresh() got context %+v, want the name and namespace kept", prod)
+	}
+	if got := config.Clusters[prod.Cluster].Server; got != "https://new.example.org" {
+		t.Errorf("runRefresh() got server %s", got)
+	}
+	if got := config.AuthInfos[prod.AuthInfo].Token; got != "new-token" {
+		t.Errorf("runRefresh() got token %s", got)
+	}
+	if got := config.AuthInfos[config.Contexts["one"].AuthInfo].Token; got != "fake" {
+		t.Errorf("runRefresh() of the cloud context got token %s", got)
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin := contextOrigin(config, metadata, "prod"); origin.SourceStatus != sourceUnchanged || origin.Source.Context != "admin" {
+		t.Errorf("runRefresh() recorded %+v, status %s", origin.Source, origin.SourceStatus)
+	}
+
+	if _, err = runRefreshCommand(t, "root-context"); err == nil {
+		t.Errorf("runRefresh() of a context without source should fail")
+	}
+	// the other contexts are still refreshed
+	if _, err = runRefreshCommand(t, "root-context", "prod"); err == nil || err.Error() != "failed to refresh 1 of 2 contexts: root-context" {
+		t.Errorf("runRefresh() error = %v", err)
+	}
+}
+
+func Test_replaceContextCredentials(t *testing.T) {
+	config := appendMergeConfig.DeepCopy()
+	config.Clusters["pig-cluster"].InsecureSkipTLSVerify = true
+	item := refreshedContext{
+		name: "root-context",
+		config: &clientcmdapi.Config{
+			Clusters:  map[string]*clientcmdapi.Cluster{"c": {Server: "https://new.org", CertificateAuthorityData: []byte("ca")}},
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{"u": {Token: "new"}},
+		},
+		context: &clientcmdapi.Context{Cluster: "c", AuthInfo: "u"},
+	}
+	if err := replaceContextCredentials(config, item); err != nil {
+		t.Fatalf("replaceContextCredentials() error = %v", err)
+	}
+	ctx := config.Contexts["root-context"]
+	cluster := config.Clusters[ctx.Cluster]
+	if ctx.Cluster != "pig-cluster" || ctx.AuthInfo != "black-user" || ctx.Namespace != "saw-ns" {
+		t.Errorf("replaceContextCredentials() changed the context %+v", ctx)
+	}
+	if cluster.Server != "https://new.org" || !cluster.InsecureSkipTLSVerify || cluster.CertificateAuthorityData != nil {
+		t.Errorf("replaceContextCredentials() got cluster %+v", cluster)
+	}
+	if config.AuthInfos["black-user"].Token != "new" {
+		t.Errorf("replaceContextCredentials() got user %+v", config.AuthInfos["black-user"])
+	}
+	item.context = &clientcmdapi.Context{Cluster: "missing", AuthInfo: "u"}
+	if err := replaceContextCredentials(config, item); err == nil {
+		t.Errorf("replaceContextCredentials() should fail when the cluster is missing")
+	}
+}
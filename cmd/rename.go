@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
 	"slices"
 
 	"github.com/spf13/cobra"
@@ -64,14 +65,16 @@ func (rc *RenameCommand) runRename(command *cobra.Command, args []string) error
 		kubeName = kubeItems[num].Name
 		rename = PromptUI("Rename", kubeName)
 	}
-	config, err = renameComplete(rename, kubeName, config)
-	if err != nil {
+	err = ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+		_, err := renameComplete(rename, kubeName, config)
 		return err
-	}
-	err = WriteConfig(true, cfgFile, config)
-	if err != nil {
+	})
+	if err != nil || dryRun {
 		return err
 	}
+	if err = renameMetadata(cfgFile, kubeName, rename); err != nil {
+		printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to rename the tags of 「%s」: %v\n", kubeName, err))
+	}
 	return MacNotifier(fmt.Sprintf("Rename [%s] to [%s]\n", kubeName, rename))
 }
 
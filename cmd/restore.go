@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bndr/gotabulate"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RestoreCommand restore cmd struct
+type RestoreCommand struct {
+	BaseCommand
+}
+
+// Init RestoreCommand
+func (rc *RestoreCommand) Init() {
+	rc.command = &cobra.Command{
+		Use:   "restore [ID]",
+		Short: "Restore the kubeconfig from a backup",
+		Long: `
+Restore the kubeconfig from a backup taken before each kubecm write
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rc.runRestore(cmd, args)
+		},
+		Example: restoreExample(),
+	}
+	rc.command.Flags().BoolP("list", "l", false, "list the backups of the kubeconfig")
+	rc.AddCommands(&DocsCommand{})
+}
+
+func (rc *RestoreCommand) runRestore(command *cobra.Command, args []string) error {
+	backups, err := listBackups(cfgFile)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return errors.New("no backup found for 「" + cfgFile + "」")
+	}
+	list, _ := rc.command.Flags().GetBool("list")
+	if list {
+		return printBackupTable(backups)
+	}
+
+	// the kubeconfig the backup replaces, the contexts changed meanwhile are kept
+	current, err := loadConfigFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	var backup *Backup
+	if len(args) == 1 {
+		backup, err = findBackup(cfgFile, args[0])
+		if err != nil {
+			return err
+		}
+	} else {
+		backup = &backups[selectBackup(backups, "Select Backup")]
+		confirm, _ := strconv.ParseBool(BoolUI(fmt.Sprintf("Are you sure you want to restore 「%s」 from 「%s」?", cfgFile, backup.ID)))
+		if !confirm {
+			return errors.New("nothing restored！")
+		}
+	}
+	config, err := clientcmd.LoadFromFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	err = WriteConfig(true, cfgFile, current, config)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored from backup 「%s」\n", backup.ID)
+	return MacNotifier(fmt.Sprintf("Restored from backup [%s]\n", backup.ID))
+}
+
+func printBackupTable(backups []Backup) error {
+	var table [][]string
+	for _, b := range backups {
+		table = append(table, []string{b.ID, b.Time.Format("2006-01-02 15:04:05"), b.Command, strconv.Itoa(b.Contexts)})
+	}
+	if table == nil {
+		return errors.New("backup not found")
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"ID", "TIME", "COMMAND", "CONTEXTS"})
+	tabulate.SetAlign("center")
+	fmt.Println(tabulate.Render("grid", "left"))
+	return nil
+}
+
+func selectBackup(backups []Backup, label string) int {
+	templates := &promptui.SelectTemplates{
+		Label:    "{{ . }}",
+		Active:   "\U0001F63C {{ .ID | red }}",
+		Inactive: "  {{ .ID | cyan }}",
+		Selected: "\U0001F638 Select:{{ .ID | green }}",
+		Details: `
+--------- Info ----------
+{{ "Time:" | faint }}	{{ .Time.Format "2006-01-02 15:04:05" }}
+{{ "Command:" | faint }}	{{ .Command }}
+{{ "Contexts:" | faint }}	{{ .Contexts }}`,
+	}
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     backups,
+		Templates: templates,
+		Size:      uiSize,
+	}
+	i, _, err := prompt.Run()
+	if err != nil {
+		log.Fatalf("Prompt failed %v\n", err)
+	}
+	return i
+}
+
+func restoreExample() string {
+	return `
+# Select a backup and restore it interactively
+kubecm restore
+# List the backups of the kubeconfig
+kubecm restore --list
+# Restore the backup with the given ID
+kubecm restore 20240101-150405.000000
+# Restore a backup, keeping the latest 20 backups of the replaced kubeconfig (0 disables backups)
+kubecm restore 20240101-150405.000000 --backup-limit 20
+`
+}
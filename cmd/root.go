@@ -39,6 +39,10 @@ var (
 	macNotify    bool
 	silenceTable bool
 	cfgCreate    bool
+	backupLimit  int
+	dryRun       bool
+	// cmdName is the name of the running command, recorded with every backup.
+	cmdName string
 )
 
 // Cli cmd struct
@@ -53,6 +57,9 @@ func NewCli() *Cli {
 			Use:   "kubecm",
 			Short: "KubeConfig Manager.",
 			Long:  printLogo(),
+			PersistentPreRun: func(cmd *cobra.Command, args []string) {
+				cmdName = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+			},
 		},
 	}
 	cli.rootCmd.SetOut(os.Stdout)
@@ -76,6 +83,8 @@ func (cli *Cli) setFlags() {
 	flags.IntVarP(&uiSize, "ui-size", "u", 10, "number of list items to show in menu at once")
 	flags.BoolVarP(&silenceTable, "silence-table", "s", false, "enable/disable output of context table on successful config update")
 	flags.BoolVarP(&macNotify, "mac-notify", "m", false, "enable to display Mac notification banner")
+	flags.BoolVar(&dryRun, "dry-run", false, "print the changes of kubeconfig without writing anything")
+	flags.IntVar(&backupLimit, "backup-limit", 10, "number of kubeconfig backups to keep before each write, 0 disables backups")
 }
 
 // Run command
@@ -104,6 +113,15 @@ func homeDir() string {
 	return homeUnix()
 }
 
+// kubecmHome returns the directory where kubecm keeps its own state,
+// it can be overridden by the KUBECM_HOME environment variable.
+func kubecmHome() string {
+	if dir := os.Getenv("KUBECM_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(homeDir(), ".kube", "kubecm")
+}
+
 func homeUnix() string {
 	if home := os.Getenv("HOME"); home != "" {
 		return home
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// shellEnv is set to the context name inside a kubecm shell
+	shellEnv = "KUBECM_SHELL"
+	// shellOriginEnv is set to the kubeconfig the context of a kubecm shell was exported from
+	shellOriginEnv = "KUBECM_SHELL_ORIGIN"
+)
+
+// ShellCommand shell cmd struct
+type ShellCommand struct {
+	BaseCommand
+}
+
+// Init ShellCommand
+func (sc *ShellCommand) Init() {
+	sc.command = &cobra.Command{
+		Use:   "shell [context]",
+		Short: "Open a sub-shell bound to a context with a private kubeconfig",
+		Long: `
+Open a sub-shell bound to a context with a private kubeconfig,
+switching context or namespace inside it does not affect other terminals
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.runShell(cmd, args)
+		},
+		Example: shellExample(),
+	}
+	sc.command.Flags().BoolP("yes", "y", false, "open a shell of a protected context without confirmation")
+	sc.AddCommands(&DocsCommand{})
+}
+
+func (sc *ShellCommand) runShell(command *cobra.Command, args []string) error {
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		config, err = handleOperation(config)
+	} else {
+		var name string
+		if name, err = resolveContext(config, args[0]); err == nil {
+			config, err = handleQuickSwitch(config, name)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	yes, _ := sc.command.Flags().GetBool("yes")
+	if err = confirmProtected(config.CurrentContext, yes); err != nil {
+		return err
+	}
+	return startShell(config, config.CurrentContext)
+}
+
+// startShell run $SHELL with KUBECONFIG pointed to a temporary kubeconfig holding only the context,
+// the file and the kubecm files of it are removed when the shell exits
+func startShell(config *clientcmdapi.Config, context string) error {
+	file, err := newShellConfig(config, context)
+	if err != nil {
+		return err
+	}
+	defer removeShellConfig(file)
+
+	shell := exec.Command(shellPath())
+	shell.Stdin, shell.Stdout, shell.Stderr = os.Stdin, os.Stdout, os.Stderr
+	shell.Env = append(os.Environ(), "KUBECONFIG="+file, shellEnv+"="+context, shellOriginEnv+"="+originConfig())
+
+	// the shell handles Ctrl-C itself, kubecm stays alive to clean up the kubeconfig, also when the
+	// terminal is closed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	printString(os.Stdout, fmt.Sprintf("Entering shell of context 「%s」, type `exit` to leave.\n", context))
+	if err = shell.Start(); err != nil {
+		return err
+	}
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGTERM || sig == syscall.SIGHUP {
+				_ = shell.Process.Signal(sig)
+			}
+		}
+	}()
+	err = shell.Wait()
+	printString(os.Stdout, fmt.Sprintf("Left shell of context 「%s」\n", context))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// the exit code of the last command in the shell is not an error of kubecm
+		return nil
+	}
+	return err
+}
+
+// originConfig return the kubeconfig file of cfgFile holding the metadata of its contexts, the one the
+// private kubeconfig of kubecm shell was exported from inside the shell
+func originConfig() string {
+	if origin := os.Getenv(shellOriginEnv); origin != "" && os.Getenv(shellEnv) != "" && cfgFile == os.Getenv("KUBECONFIG") {
+		return origin
+	}
+	return cfgFile
+}
+
+// newShellConfig write the context into a private temporary kubeconfig
+func newShellConfig(config *clientcmdapi.Config, context string) (string, error) {
+	shellConfig, err := exportContext([]string{context}, config)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "kubecm-shell-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	if err = clientcmd.WriteToFile(*shellConfig, f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), os.Chmod(f.Name(), 0600)
+}
+
+// removeShellConfig remove the private kubeconfig of the shell, with the history, metadata and lock
+// files kubecm keeps for it
+func removeShellConfig(file string) {
+	// the lock paths resolve the symlinks of the files, before they are removed
+	files := []string{lockPath(file), historyFile(file), lockPath(historyFile(file)),
+		metadataFile(file), lockPath(metadataFile(file)), file}
+	for _, f := range files {
+		_ = os.Remove(f)
+	}
+}
+
+// shellPath return the shell of the user
+func shellPath() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		if shell := os.Getenv("COMSPEC"); shell != "" {
+			return shell
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+func shellExample() string {
+	return `
+# Select a context and open a sub-shell bound to it
+kubecm shell
+# Open a sub-shell bound to the dev context
+kubecm shell dev
+# Open a sub-shell of a protected context without confirmation
+kubecm shell prod --yes
+# The context is available in the sub-shell, e.g. for the prompt
+echo $KUBECM_SHELL
+`
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func Test_newShellConfig(t *testing.T) {
+	file, err := newShellConfig(appendMergeConfig.DeepCopy(), "federal-context")
+	if err != nil {
+		t.Fatalf("newShellConfig() error = %v", err)
+	}
+	defer os.Remove(file)
+	got, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Contexts) != 1 || got.CurrentContext != "federal-context" {
+		t.Errorf("newShellConfig() got contexts %v, current-context %q", got.Contexts, got.CurrentContext)
+	}
+	if _, ok := got.AuthInfos["red-user"]; !ok {
+		t.Errorf("newShellConfig() missing user red-user")
+	}
+	if runtime.GOOS != "windows" {
+		info, _ := os.Stat(file)
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("newShellConfig() mode = %v, want 0600", info.Mode().Perm())
+		}
+	}
+	if _, err = newShellConfig(appendMergeConfig.DeepCopy(), "not-exist"); err == nil {
+		t.Errorf("newShellConfig() want error")
+	}
+}
+
+func Test_startShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	script, err := os.CreateTemp(t.TempDir(), "shell-*.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := script.Name() + ".out"
+	// record the environment of the sub-shell
+	_, _ = script.WriteString("#!" + sh + "\necho \"$KUBECONFIG $KUBECM_SHELL\" > " + out + "\nexit 3\n")
+	script.Close()
+	if err = os.Chmod(script.Name(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELL", script.Name())
+
+	if err = startShell(appendMergeConfig.DeepCopy(), "root-context"); err != nil {
+		t.Fatalf("startShell() error = %v", err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file, context string
+	if _, err = fmt.Sscan(string(content), &file, &context); err != nil {
+		t.Fatalf("unexpected shell output %q", content)
+	}
+	if context != "root-context" {
+		t.Errorf("KUBECM_SHELL got = %q, want %q", context, "root-context")
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("startShell() did not remove the private kubeconfig %s", file)
+	}
+}
+
+func Test_startShell_hangup(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil || runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	script := filepath.Join(t.TempDir(), "shell.sh")
+	out := script + ".out"
+	// the terminal is closed while the shell runs, kubecm forwards the hangup to it
+	content := "#!" + sh + "\necho \"$KUBECONFIG\" > " + out + "\nkill -HUP $PPID\nexec sleep 10\n"
+	if err = os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELL", script)
+
+	if err = startShell(appendMergeConfig.DeepCopy(), "root-context"); err != nil {
+		t.Fatalf("startShell() error = %v", err)
+	}
+	file, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(strings.TrimSpace(string(file))); !os.IsNotExist(err) {
+		t.Errorf("startShell() did not remove the private kubeconfig %s on hangup", file)
+	}
+}
+
+func Test_removeShellConfig(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file, err := newShellConfig(appendMergeConfig.DeepCopy(), "root-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a switch and a tag inside the shell keep files for the private kubeconfig
+	if err = recordHistory(file, HistoryEntry{Context: "root-context"}); err != nil {
+		t.Fatal(err)
+	}
+	err = updateMetadata(file, func(metadata map[string]*ContextMetadata) error {
+		metadata["root-context"] = &ContextMetadata{Tags: map[string]string{"env": "dev"}}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []string{file, historyFile(file), metadataFile(file), lockPath(historyFile(file)), lockPath(metadataFile(file))}
+	removeShellConfig(file)
+	for _, f := range files {
+		if _, err = os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("removeShellConfig() kept %s", f)
+		}
+	}
+}
+
+func Test_runShell_protected(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	t.Setenv(protectedEnv, "root-context")
+	defer func(f func() bool) { stdinIsTerminal = f }(stdinIsTerminal)
+	stdinIsTerminal = func() bool { return false }
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(appendMergeConfig, cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	sc := &ShellCommand{}
+	sc.Init()
+	sc.command.SetArgs([]string{"root-context"})
+	sc.command.SilenceUsage = true
+	sc.command.SilenceErrors = true
+	if err := sc.command.Execute(); ExitCode(err) != ExitProtected {
+		t.Errorf("runShell() error = %v, want exit code %d for a protected context", err, ExitProtected)
+	}
+}
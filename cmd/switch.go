@@ -3,10 +3,14 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"os"
 	"slices"
+	"sort"
+	"strings"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
+	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -22,7 +26,8 @@ func (sc *SwitchCommand) Init() {
 		Use:   "switch",
 		Short: "Switch Kube Context interactively",
 		Long: `
-Switch Kube Context interactively
+Switch Kube Context interactively.
+Switching to a protected context asks to type its name, and exits with code 3 when it is not confirmed.
 `,
 		Aliases: []string{"s", "sw"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -34,8 +39,12 @@ Switch Kube Context interactively
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return sc.runSwitch(cmd, args)
 		},
-		Example: switchExample(),
+		Example:           switchExample(),
+		ValidArgsFunction: completeSwitchArgs,
 	}
+	sc.command.Flags().Bool("shell", false, "open a sub-shell bound to the context instead of changing the kubeconfig")
+	sc.command.Flags().BoolP("yes", "y", false, "switch to a protected context without confirmation")
+	sc.command.Flags().StringArray("tag", nil, "only switch among the contexts with the tag, key=value or key, can be repeated")
 	sc.AddCommands(&DocsCommand{})
 }
 
@@ -44,24 +53,179 @@ func (sc *SwitchCommand) runSwitch(command *cobra.Command, args []string) error
 	if err != nil {
 		return err
 	}
+	from := config.CurrentContext
+	// candidates are the contexts to choose from, narrowed by --tag
+	candidates := config
+	if tags, _ := sc.command.Flags().GetStringArray("tag"); len(tags) > 0 {
+		metadata, err := loadMetadata(cfgFile)
+		if err != nil {
+			return err
+		}
+		candidates, err = filterContextsByTags(config, metadata, tags)
+		if err != nil {
+			return err
+		}
+	}
+	var namespace string
 	switch len(args) {
 	case 0:
-		config, err = handleOperation(config)
+		candidates, err = handleOperation(candidates)
+		if err != nil {
+			return err
+		}
+		config, err = handleQuickSwitch(config, candidates.CurrentContext)
 		if err != nil {
 			return err
 		}
 	case 1:
-		config, err = handleQuickSwitch(config, args[0])
+		name := args[0]
+		if name == "-" {
+			name, err = previousContext(cfgFile, from)
+			if err != nil {
+				return err
+			}
+		}
+		name, namespace = splitContextNamespace(candidates, name)
+		name, err = resolveContext(candidates, name)
+		if err != nil {
+			return err
+		}
+		config, err = handleQuickSwitch(config, name)
 		if err != nil {
 			return err
 		}
 	}
-	err = WriteConfig(true, cfgFile, config)
+	name := config.CurrentContext
+	if namespace != "" {
+		if err = checkContextNamespace(config, name, namespace); err != nil {
+			return err
+		}
+	}
+	yes, _ := sc.command.Flags().GetBool("yes")
+	if err = confirmProtected(name, yes); err != nil {
+		return err
+	}
+	if shell, _ := sc.command.Flags().GetBool("shell"); shell {
+		if namespace != "" {
+			config.Contexts[name].Namespace = namespace
+		}
+		printCertExpiryWarning(os.Stdout, config, name)
+		return startShell(config, name)
+	}
+	return switchContext(config, from, name, namespace)
+}
+
+// switchContext write the current context, and its namespace if not empty, then record the switch in the history
+func switchContext(config *clientcmdapi.Config, from, name, namespace string) error {
+	err := ApplyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+		_, err := handleQuickSwitch(config, name)
+		if err != nil || namespace == "" {
+			return err
+		}
+		return setNamespace(config, name, namespace)
+	})
+	if err != nil || dryRun {
+		return err
+	}
+	if namespace != "" {
+		var records []HistoryEntry
+		if ctx, ok := config.Contexts[from]; ok {
+			records = append(records, HistoryEntry{Context: from, Namespace: ctx.Namespace})
+		}
+		err = recordHistory(cfgFile, append(records, HistoryEntry{Context: name, Namespace: namespace})...)
+	} else {
+		err = recordSwitch(cfgFile, config, from, name)
+	}
+	if err != nil {
+		printYellow(os.Stdout, fmt.Sprintf("WARNING: failed to record the switch history: %v\n", err))
+	}
+	if namespace != "" {
+		fmt.Printf("Switched to context 「%s」 and namespace 「%s」\n", name, namespace)
+		printCertExpiryWarning(os.Stdout, config, name)
+		return MacNotifier(fmt.Sprintf("Switched to context [%s] and namespace [%s]\n", name, namespace))
+	}
+	fmt.Printf("Switched to context 「%s」\n", name)
+	printCertExpiryWarning(os.Stdout, config, name)
+	return MacNotifier(fmt.Sprintf("Switched to context [%s]\n", name))
+}
+
+// splitContextNamespace split context/namespace or context:namespace, the context may be partial and
+// is resolved afterwards. A name that is itself a context, or matches a context with the separator in
+// its name, is never split
+func splitContextNamespace(config *clientcmdapi.Config, name string) (string, string) {
+	if _, ok := config.Contexts[name]; ok {
+		return name, ""
+	}
+	i := strings.LastIndexAny(name, "/:")
+	if i <= 0 || i == len(name)-1 {
+		return name, ""
+	}
+	if _, ok := config.Contexts[name[:i]]; ok {
+		return name[:i], name[i+1:]
+	}
+	if len(fuzzyMatchContexts(config, name)) > 0 || len(fuzzyMatchContexts(config, name[:i])) == 0 {
+		return name, ""
+	}
+	return name[:i], name[i+1:]
+}
+
+// checkContextNamespace check that the namespace exists in the cluster of the context
+func checkContextNamespace(config *clientcmdapi.Config, context, namespace string) error {
+	clientset, err := GetClientSetForContext(config, context)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Switched to context 「%s」\n", config.CurrentContext)
-	return MacNotifier(fmt.Sprintf("Switched to context [%s]\n", config.CurrentContext))
+	exist, err := CheckNamespaceExist(namespace, clientset)
+	if err != nil || !exist {
+		return fmt.Errorf("can not find namespace 「%s」 in context 「%s」", namespace, context)
+	}
+	return nil
+}
+
+// completeSwitchArgs complete context names, and context/namespace once a context and / are typed
+func completeSwitchArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var completions []string
+	if i := strings.LastIndexAny(toComplete, "/:"); i > 0 {
+		if _, ok := config.Contexts[toComplete[:i]]; ok {
+			clientset, err := GetClientSetForContext(config, toComplete[:i])
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			namespaces, err := GetNamespaceList("", clientset)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			for _, ns := range namespaces {
+				completions = append(completions, toComplete[:i+1]+ns.Name)
+			}
+			return completions, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+	return completeContexts(cmd, args, toComplete)
+}
+
+// completeContexts complete the first argument with context names
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	var completions []string
+	for name := range config.Contexts {
+		completions = append(completions, name)
+	}
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoFileComp
 }
 
 func handleQuickSwitch(config *clientcmdapi.Config, name string) (*clientcmdapi.Config, error) {
@@ -73,9 +237,24 @@ func handleQuickSwitch(config *clientcmdapi.Config, name string) (*clientcmdapi.
 }
 
 func handleOperation(config *clientcmdapi.Config) (*clientcmdapi.Config, error) {
+	var names []string
+	for key := range config.Contexts {
+		names = append(names, key)
+	}
+	kubeName, err := selectContext(config, names)
+	if err != nil {
+		return config, err
+	}
+	config.CurrentContext = kubeName
+	return config, nil
+}
+
+// selectContext select one of the named contexts interactively, recently used contexts first
+func selectContext(config *clientcmdapi.Config, names []string) (string, error) {
 	var kubeItems []Needle
 	current := config.CurrentContext
-	for key, obj := range config.Contexts {
+	for _, key := range names {
+		obj := config.Contexts[key]
 		if key != current {
 			kubeItems = append(kubeItems, Needle{Name: key, Cluster: obj.Cluster, User: obj.AuthInfo})
 		} else {
@@ -83,15 +262,45 @@ func handleOperation(config *clientcmdapi.Config) (*clientcmdapi.Config, error)
 		}
 	}
 	slices.SortFunc(kubeItems, compareKubeItems)
+	kubeItems = sortByRecent(kubeItems, func(n Needle) string { return n.Name }, recentContexts(cfgFile))
 	// exit option
 	kubeItems, err := ExitOption(kubeItems)
 	if err != nil {
-		return config, err
+		return "", err
 	}
 	num := SelectUI(kubeItems, "Select Kube Context")
-	kubeName := kubeItems[num].Name
-	config.CurrentContext = kubeName
-	return config, nil
+	return kubeItems[num].Name, nil
+}
+
+// resolveContext return the context matching name exactly, or the only context matching it fuzzily.
+// Several fuzzy matches are offered in the picker, or returned as an error when stdin is not a terminal.
+func resolveContext(config *clientcmdapi.Config, name string) (string, error) {
+	if _, ok := config.Contexts[name]; ok {
+		return name, nil
+	}
+	matches := fuzzyMatchContexts(config, name)
+	switch {
+	case len(matches) == 0:
+		return "", errors.New("cannot find context named 「" + name + "」")
+	case len(matches) == 1:
+		return matches[0], nil
+	case !stdinIsTerminal():
+		return "", fmt.Errorf("「%s」 matches more than one context, candidates:\n  %s", name, strings.Join(matches, "\n  "))
+	}
+	return selectContext(config, matches)
+}
+
+// fuzzyMatchContexts return the sorted context names matching the input like the search of the picker
+func fuzzyMatchContexts(config *clientcmdapi.Config, input string) []string {
+	input = strings.Replace(strings.ToLower(input), " ", "", -1)
+	var matches []string
+	for key := range config.Contexts {
+		if fuzzy.Match(input, strings.Replace(strings.ToLower(key), " ", "", -1)) {
+			matches = append(matches, key)
+		}
+	}
+	sort.Strings(matches)
+	return matches
 }
 
 //TODO need update docs
@@ -102,5 +311,20 @@ func switchExample() string {
 kubecm switch
 # Quick switch Kube Context
 kubecm switch dev
+# Switch to the only Kube Context matching a part of its name, e.g. prod-eu-west-1
+kubecm switch prod-eu
+# Switch Kube Context and namespace in one step
+kubecm switch dev/kube-system
+# or
+kubecm switch dev:kube-system
+# Switch among the Kube Contexts tagged env=prod, see kubecm tag
+kubecm switch --tag env=prod
+# Switch to a protected Kube Context without typing its name, e.g. in scripts
+# contexts are protected by KUBECM_PROTECTED_CONTEXTS="*prod*,live-?" or kubecm tag add <context> protected
+kubecm switch prod --yes
+# Switch back to the previous Kube Context
+kubecm switch -
+# Switch Kube Context only in a sub-shell, other terminals are not affected
+kubecm switch --shell dev
 `
 }
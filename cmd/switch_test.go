@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -55,3 +56,76 @@ func Test_handleQuickSwitch(t *testing.T) {
 		})
 	}
 }
+
+func Test_splitContextNamespace(t *testing.T) {
+	config := &clientcmdapi.Config{
+		Contexts: map[string]*clientcmdapi.Context{
+			"dev":                         {},
+			"arn:aws:eks:us-east-1:1:c/x": {},
+			"kind:dev":                    {},
+		},
+	}
+	tests := []struct {
+		name          string
+		arg           string
+		wantContext   string
+		wantNamespace string
+	}{
+		{"context", "dev", "dev", ""},
+		{"slash", "dev/kube-system", "dev", "kube-system"},
+		{"colon", "dev:kube-system", "dev", "kube-system"},
+		{"context-with-separators", "arn:aws:eks:us-east-1:1:c/x", "arn:aws:eks:us-east-1:1:c/x", ""},
+		{"context-with-colon-and-namespace", "kind:dev/default", "kind:dev", "default"},
+		{"unknown-context", "test/default", "test/default", ""},
+		{"partial-context", "de/kube-system", "de", "kube-system"},
+		{"partial-context-with-separators", "eks:us-east-1:1:c/x", "eks:us-east-1:1:c/x", ""},
+		{"empty-namespace", "dev/", "dev/", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotContext, gotNamespace := splitContextNamespace(config, tt.arg)
+			if gotContext != tt.wantContext || gotNamespace != tt.wantNamespace {
+				t.Errorf("splitContextNamespace() got = %v, %v, want %v, %v", gotContext, gotNamespace, tt.wantContext, tt.wantNamespace)
+			}
+		})
+	}
+}
+
+func Test_resolveContext(t *testing.T) {
+	defer func(f func() bool) { stdinIsTerminal = f }(stdinIsTerminal)
+	stdinIsTerminal = func() bool { return false }
+	config := &clientcmdapi.Config{
+		Contexts: map[string]*clientcmdapi.Context{
+			"prod-eu-west-1": {},
+			"prod-us-east-1": {},
+			"dev-eu-west-1":  {},
+			"prod":           {},
+		},
+	}
+	tests := []struct {
+		name    string
+		arg     string
+		want    string
+		wantErr string
+	}{
+		{"exact", "prod", "prod", ""},
+		{"one-match", "prod-eu", "prod-eu-west-1", ""},
+		{"case-insensitive", "DEV", "dev-eu-west-1", ""},
+		{"several-matches", "eu-west", "", "dev-eu-west-1\n  prod-eu-west-1"},
+		{"no-match", "test", "", "cannot find context named 「test」"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveContext(config, tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("resolveContext() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveContext() got = %v, error = %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
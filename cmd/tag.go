@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// TagCommand tag command struct
+type TagCommand struct {
+	BaseCommand
+}
+
+// Init TagCommand
+func (tc *TagCommand) Init() {
+	tc.command = &cobra.Command{
+		Use:   "tag [COMMANDS]",
+		Short: "Manage tags and descriptions of contexts",
+		Long: `
+Manage tags and descriptions of contexts, they are kept by kubecm and not written to the kubeconfig.
+The tag env is the environment label of a context, e.g. env=prod,
+and switching to a context with the tag protected asks for confirmation.
+`,
+	}
+	tc.AddCommands(&TagAddCommand{})
+	tc.AddCommands(&TagRemoveCommand{})
+	tc.AddCommands(&TagListCommand{})
+	tc.AddCommands(&DocsCommand{})
+}
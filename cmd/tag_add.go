@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// TagAddCommand tag add command struct
+type TagAddCommand struct {
+	TagCommand
+}
+
+// Init TagAddCommand
+func (ta *TagAddCommand) Init() {
+	ta.command = &cobra.Command{
+		Use:   "add <context> [key=value...]",
+		Short: "Add tags or a description to a context",
+		Long:  "Add tags or a description to a context, existing tags with the same key are replaced",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ta.runTagAdd(cmd, args)
+		},
+		Example:           tagAddExample(),
+		ValidArgsFunction: completeContexts,
+	}
+	ta.command.Flags().StringP("description", "d", "", "description of the context")
+}
+
+func (ta *TagAddCommand) runTagAdd(cmd *cobra.Command, args []string) error {
+	description, _ := ta.command.Flags().GetString("description")
+	if len(args) == 1 && description == "" {
+		return errors.New("no tags or description to add")
+	}
+	config, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	if _, ok := config.Contexts[name]; !ok {
+		return errors.New("cannot find context named 「" + name + "」")
+	}
+	tags, err := parseTags(args[1:])
+	if err != nil {
+		return err
+	}
+	err = updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+		meta, ok := metadata[name]
+		if !ok {
+			meta = &ContextMetadata{}
+			metadata[name] = meta
+		}
+		if meta.Tags == nil {
+			meta.Tags = make(map[string]string)
+		}
+		for key, value := range tags {
+			meta.Tags[key] = value
+		}
+		if description != "" {
+			meta.Description = description
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Context 「%s」 is updated\n", name)
+	return nil
+}
+
+func tagAddExample() string {
+	return `
+# Label the context as production
+kubecm tag add my-context env=prod
+# Add several tags and a description
+kubecm tag add my-context env=prod team=payments critical -d "payments production cluster in eu-west-1"
+`
+}
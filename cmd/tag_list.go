@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/bndr/gotabulate"
+	"github.com/spf13/cobra"
+)
+
+// TagListCommand tag ls command struct
+type TagListCommand struct {
+	TagCommand
+}
+
+// ContextTags tags record of kubecm tag ls
+type ContextTags struct {
+	Name        string            `json:"name"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+// Init TagListCommand
+func (tl *TagListCommand) Init() {
+	tl.command = &cobra.Command{
+		Use:     "ls [context...]",
+		Short:   "List the tags of contexts",
+		Long:    "List the tags and descriptions of contexts",
+		Aliases: []string{"list"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tl.runTagList(cmd, args)
+		},
+		Example:           tagListExample(),
+		ValidArgsFunction: completeContexts,
+	}
+	tl.command.Flags().StringP("output", "o", "", "output format, available values: json, yaml, name")
+}
+
+func (tl *TagListCommand) runTagList(cmd *cobra.Command, args []string) error {
+	output, _ := tl.command.Flags().GetString("output")
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+	metadata, err := loadMetadata(cfgFile)
+	if err != nil {
+		return err
+	}
+	return printContextTags(os.Stdout, contextTags(metadata, args), output)
+}
+
+// contextTags return the tags of the named contexts, or of all the contexts, sorted by name
+func contextTags(metadata map[string]*ContextMetadata, names []string) []ContextTags {
+	var records []ContextTags
+	for name, meta := range metadata {
+		if len(names) > 0 && !slices.Contains(names, name) {
+			continue
+		}
+		records = append(records, ContextTags{Name: name, Tags: meta.Tags, Description: meta.Description})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+	return records
+}
+
+func printContextTags(out io.Writer, records []ContextTags, output string) error {
+	if len(records) == 0 {
+		return errors.New("no tags found")
+	}
+	switch output {
+	case OutputJSON, OutputYAML:
+		return printStructured(out, output, records)
+	case OutputName:
+		for _, record := range records {
+			fmt.Fprintln(out, record.Name)
+		}
+		return nil
+	}
+	var table [][]string
+	for _, record := range records {
+		table = append(table, []string{record.Name, record.Tags[envTag], formatTags(record.Tags), record.Description})
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders([]string{"CONTEXT", "ENV", "TAGS", "DESCRIPTION"})
+	tabulate.SetAlign("center")
+	fmt.Fprintln(out, tabulate.Render("grid", "left"))
+	return nil
+}
+
+func tagListExample() string {
+	return `
+# List the tags of all the contexts
+kubecm tag ls
+# List the tags of some contexts
+kubecm tag ls my-context1 my-context2
+# Output the tags as json
+kubecm tag ls -o json
+`
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// TagRemoveCommand tag rm command struct
+type TagRemoveCommand struct {
+	TagCommand
+}
+
+// Init TagRemoveCommand
+func (tr *TagRemoveCommand) Init() {
+	tr.command = &cobra.Command{
+		Use:     "rm <context> [key...]",
+		Short:   "Remove tags from a context",
+		Long:    "Remove tags from a context, all the tags and the description are removed when no key is given",
+		Aliases: []string{"remove"},
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return tr.runTagRemove(cmd, args)
+		},
+		Example:           tagRemoveExample(),
+		ValidArgsFunction: completeContexts,
+	}
+	tr.command.Flags().BoolP("description", "d", false, "remove the description")
+}
+
+func (tr *TagRemoveCommand) runTagRemove(cmd *cobra.Command, args []string) error {
+	description, _ := tr.command.Flags().GetBool("description")
+	name := args[0]
+	tags, err := parseTags(args[1:])
+	if err != nil {
+		return err
+	}
+	err = updateMetadata(cfgFile, func(metadata map[string]*ContextMetadata) error {
+		meta, ok := metadata[name]
+		if !ok {
+			return errors.New("context 「" + name + "」 has no tags")
+		}
+		if len(tags) == 0 && !description {
+			delete(metadata, name)
+			return nil
+		}
+		for key := range tags {
+			if _, ok := meta.Tags[key]; !ok {
+				return fmt.Errorf("context 「%s」 has no tag %s", name, key)
+			}
+			delete(meta.Tags, key)
+		}
+		if description {
+			meta.Description = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Context 「%s」 is updated\n", name)
+	return nil
+}
+
+func tagRemoveExample() string {
+	return `
+# Remove the tags env and team from the context
+kubecm tag rm my-context env team
+# Remove the description of the context
+kubecm tag rm my-context -d
+# Remove all the tags and the description of the context
+kubecm tag rm my-context
+`
+}
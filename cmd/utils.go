@@ -12,17 +12,23 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	r "runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/BussanQ/kubecm/pkg/utils"
 	"k8s.io/client-go/rest"
 
 	"github.com/bndr/gotabulate"
 	ct "github.com/daviddengcn/go-colortext"
 	"github.com/imdario/mergo"
 	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	v "k8s.io/apimachinery/pkg/version"
@@ -110,21 +116,32 @@ func HashSufString(data string) string {
 	return sum
 }
 
-// PrintTable generate table
-func PrintTable(config *clientcmdapi.Config) error {
-	var table [][]string
+// ContextInfo context record of kubecm list
+type ContextInfo struct {
+	Current   bool              `json:"current"`
+	Name      string            `json:"name"`
+	Cluster   string            `json:"cluster"`
+	User      string            `json:"user"`
+	Server    string            `json:"server"`
+	Namespace string            `json:"namespace"`
+	AuthType  string            `json:"authType"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Check     *ProbeResult      `json:"check,omitempty"`
+}
+
+// contextInfos return the records of the contexts sorted by name, skipping the ones without cluster
+func contextInfos(config *clientcmdapi.Config) []ContextInfo {
+	var infos []ContextInfo
 	sortedKeys := make([]string, 0)
 	for k := range config.Contexts {
 		sortedKeys = append(sortedKeys, k)
 	}
 	sort.Strings(sortedKeys)
 	ctx := config.Contexts
+	// the tags are only decoration, a broken metadata file must not break listing
+	metadata, _ := loadMetadata(cfgFile)
 	for _, k := range sortedKeys {
 		namespace := "default"
-		head := ""
-		if config.CurrentContext == k {
-			head = "*"
-		}
 		if ctx[k].Namespace != "" {
 			namespace = ctx[k].Namespace
 		}
@@ -135,24 +152,117 @@ func PrintTable(config *clientcmdapi.Config) error {
 		if !ok {
 			continue
 		}
-		conTmp := []string{head, k, ctx[k].Cluster, ctx[k].AuthInfo, cluster.Server, namespace}
-		table = append(table, conTmp)
-	}
+		var tags map[string]string
+		if meta, ok := metadata[k]; ok {
+			tags = meta.Tags
+		}
+		infos = append(infos, ContextInfo{
+			Current:   config.CurrentContext == k,
+			Name:      k,
+			Cluster:   ctx[k].Cluster,
+			User:      ctx[k].AuthInfo,
+			Server:    cluster.Server,
+			Namespace: namespace,
+			AuthType:  authType(config.AuthInfos[ctx[k].AuthInfo]),
+			Tags:      tags,
+		})
+	}
+	return infos
+}
+
+// authType return how the user authenticates to the cluster
+func authType(authInfo *clientcmdapi.AuthInfo) string {
+	switch {
+	case authInfo == nil:
+		return "none"
+	case authInfo.Exec != nil:
+		return "exec"
+	case authInfo.AuthProvider != nil:
+		return "auth-provider"
+	case len(authInfo.ClientCertificateData) > 0 || authInfo.ClientCertificate != "":
+		return "client-certificate"
+	case authInfo.Token != "" || authInfo.TokenFile != "":
+		return "token"
+	case authInfo.Username != "":
+		return "basic"
+	}
+	return "none"
+}
 
-	if table != nil {
-		tabulate := gotabulate.Create(table)
-		tabulate.SetHeaders([]string{"CURRENT", "NAME", "CLUSTER", "USER", "SERVER", "Namespace"})
-		// Turn On String Wrapping
-		tabulate.SetWrapStrings(true)
-		// Render the table
-		tabulate.SetAlign("center")
-		fmt.Println(tabulate.Render("grid", "left"))
-	} else {
+// PrintTable generate table
+func PrintTable(config *clientcmdapi.Config) error {
+	return printContexts(os.Stdout, config, "")
+}
+
+// printContexts print the contexts in the output format of the -o flag
+func printContexts(out io.Writer, config *clientcmdapi.Config, output string) error {
+	return printContextInfos(out, contextInfos(config), output)
+}
+
+// printContextInfos print the context records in the output format of the -o flag
+func printContextInfos(out io.Writer, infos []ContextInfo, output string) error {
+	if infos == nil {
 		return errors.New("context not found")
 	}
+	switch output {
+	case OutputJSON, OutputYAML:
+		return printStructured(out, output, infos)
+	case OutputName:
+		for _, info := range infos {
+			fmt.Fprintln(out, info.Name)
+		}
+		return nil
+	}
+
+	var table [][]string
+	headers := []string{"CURRENT", "NAME", "CLUSTER", "USER", "SERVER", "Namespace"}
+	if output == OutputWide {
+		headers = append(headers, "AUTH")
+	}
+	withTags := slices.ContainsFunc(infos, func(info ContextInfo) bool { return len(info.Tags) > 0 })
+	if withTags {
+		headers = append(headers, "TAGS")
+	}
+	withCheck := slices.ContainsFunc(infos, func(info ContextInfo) bool { return info.Check != nil })
+	if withCheck {
+		headers = append(headers, "STATUS", "VERSION", "LATENCY")
+	}
+	for _, info := range infos {
+		head := ""
+		if info.Current {
+			head = "*"
+		}
+		conTmp := []string{head, info.Name, info.Cluster, info.User, info.Server, info.Namespace}
+		if output == OutputWide {
+			conTmp = append(conTmp, info.AuthType)
+		}
+		if withTags {
+			conTmp = append(conTmp, formatTags(info.Tags))
+		}
+		if withCheck {
+			if info.Check != nil {
+				conTmp = append(conTmp, info.Check.Status, info.Check.Version, info.Check.Latency)
+			} else {
+				conTmp = append(conTmp, "", "", "")
+			}
+		}
+		table = append(table, conTmp)
+	}
+	tabulate := gotabulate.Create(table)
+	tabulate.SetHeaders(headers)
+	// Turn On String Wrapping
+	tabulate.SetWrapStrings(true)
+	// Render the table
+	tabulate.SetAlign("center")
+	fmt.Fprintln(out, tabulate.Render("grid", "left"))
 	return nil
 }
 
+// stdinIsTerminal report whether stdin is a terminal that can run the select ui
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 // SelectUI output select ui
 func SelectUI(kubeItems []Needle, label string) int {
 	s, err := selectUIRunner(kubeItems, label, nil)
@@ -302,72 +412,246 @@ func ClusterStatus(duration time.Duration) (*ClusterStatusCheck, error) {
 
 // MoreInfo output more info
 func MoreInfo(clientSet kubernetes.Interface, writer io.Writer) error {
-	if os.Getenv("KUBECM_DISABLE_K8S_MORE_INFO") != "" {
+	if moreInfoDisabled() {
 		return nil
 	}
-	timeout := int64(2)
-	ctx := context.TODO()
-	nodesList, err := clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{TimeoutSeconds: &timeout})
+	kv, err := clusterSummary(clientSet)
 	if err != nil {
 		return err
 	}
-	podsList, err := clientSet.CoreV1().Pods("").List(ctx, metav1.ListOptions{TimeoutSeconds: &timeout})
-	if err != nil {
+	printKV(writer, "[Summary] ", kv)
+	return nil
+}
+
+// moreInfoDisabled report whether KUBECM_DISABLE_K8S_MORE_INFO is set
+func moreInfoDisabled() bool {
+	return os.Getenv("KUBECM_DISABLE_K8S_MORE_INFO") != ""
+}
+
+// summaryPageSize is the page size of counting the items of a list
+// when the API server does not report the remaining item count
+const summaryPageSize = 500
+
+// listFunc list a kind of resource of the cluster
+type listFunc func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+
+// clusterSummary return the number of namespaces, nodes and pods of the cluster, counted concurrently
+func clusterSummary(clientSet kubernetes.Interface) (map[string]int, error) {
+	lists := map[string]listFunc{
+		"Namespace": func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientSet.CoreV1().Namespaces().List(ctx, opts)
+		},
+		"Node": func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientSet.CoreV1().Nodes().List(ctx, opts)
+		},
+		"Pod": func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientSet.CoreV1().Pods("").List(ctx, opts)
+		},
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	kv := make(map[string]int)
+	for name, list := range lists {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, err := countItems(context.TODO(), list)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			kv[name] = count
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return kv, nil
+}
+
+// countItems count the items of a list without holding them all in memory:
+// a list of one item tells the remaining item count, otherwise the list is read page by page
+func countItems(ctx context.Context, list listFunc) (int, error) {
+	timeout := int64(2)
+	opts := metav1.ListOptions{Limit: 1, TimeoutSeconds: &timeout}
+	count := 0
+	for {
+		obj, err := list(ctx, opts)
+		if err != nil {
+			return 0, err
+		}
+		listMeta, err := meta.ListAccessor(obj)
+		if err != nil {
+			return 0, err
+		}
+		count += meta.LenList(obj)
+		if remaining := listMeta.GetRemainingItemCount(); remaining != nil {
+			return count + int(*remaining), nil
+		}
+		if listMeta.GetContinue() == "" {
+			return count, nil
+		}
+		opts.Continue = listMeta.GetContinue()
+		opts.Limit = summaryPageSize
+	}
+}
+
+// WriteConfig write kubeconfig, with cover the changes from baseConfig to outConfig are applied to
+// cfgFile so the ones written meanwhile are kept, name is the source shown in the message
+func WriteConfig(cover bool, name string, baseConfig, outConfig *clientcmdapi.Config) error {
+	if cover {
+		return ApplyConfig(name, func(config *clientcmdapi.Config) error {
+			applyConfigDiff(config, baseConfig, outConfig)
+			return nil
+		})
+	}
+	if dryRun {
+		// the diff against the file that would be replaced
+		_, err := ModifyConfig("kubecm.config", func(config *clientcmdapi.Config) error {
+			*config = *outConfig.DeepCopy()
+			return nil
+		})
 		return err
 	}
-	nsList, err := clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{TimeoutSeconds: &timeout})
+	err := writeConfigFile(outConfig, "kubecm.config")
 	if err != nil {
 		return err
 	}
-
-	kv := make(map[string]int)
-	kv["Namespace"] = len(nsList.Items)
-	kv["Node"] = len(nodesList.Items)
-	kv["Pod"] = len(podsList.Items)
-	printKV(writer, "[Summary] ", kv)
+	printString(os.Stdout, "generate ./kubecm.config\n")
 	return nil
 }
 
-// WriteConfig write kubeconfig
-func WriteConfig(cover bool, file string, outConfig *clientcmdapi.Config) error {
-	if cover {
-		err := clientcmd.WriteToFile(*outConfig, cfgFile)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("「%s」 write successful!\n", file)
+// applyConfigDiff apply the entries added, changed and removed from base to out to config
+func applyConfigDiff(config, base, out *clientcmdapi.Config) {
+	applyMapDiff(config.Clusters, base.Clusters, out.Clusters)
+	applyMapDiff(config.AuthInfos, base.AuthInfos, out.AuthInfos)
+	applyMapDiff(config.Contexts, base.Contexts, out.Contexts)
+	applyMapDiff(config.Extensions, base.Extensions, out.Extensions)
+	if base.CurrentContext != out.CurrentContext {
+		config.CurrentContext = out.CurrentContext
+	}
+	if !reflect.DeepEqual(base.Preferences, out.Preferences) {
+		config.Preferences = out.Preferences
+	}
+}
 
-		if !silenceTable {
-			err = PrintTable(outConfig)
-			if err != nil {
-				return err
-			}
+func applyMapDiff[V any](entries, base, out map[string]V) {
+	for key := range base {
+		if _, ok := out[key]; !ok {
+			delete(entries, key)
 		}
-
-	} else {
-		err := clientcmd.WriteToFile(*outConfig, "kubecm.config")
-		if err != nil {
-			return err
+	}
+	for key, value := range out {
+		if old, ok := base[key]; !ok || !reflect.DeepEqual(old, value) {
+			entries[key] = value
 		}
-		printString(os.Stdout, "generate ./kubecm.config\n")
+	}
+}
+
+// ApplyConfig apply change to cfgFile and print the result, name is the source shown in the message
+func ApplyConfig(name string, change func(config *clientcmdapi.Config) error) error {
+	outConfig, err := ModifyConfig(cfgFile, change)
+	if err != nil || dryRun {
+		return err
+	}
+	fmt.Printf("「%s」 write successful!\n", name)
+	if !silenceTable {
+		return PrintTable(outConfig)
 	}
 	return nil
 }
 
 // UpdateConfigFile update kubeconfig
-func UpdateConfigFile(file string, updateConfig *clientcmdapi.Config) error {
+func UpdateConfigFile(file string, change func(config *clientcmdapi.Config) error) error {
 	file, err := CheckAndTransformFilePath(file, cfgCreate)
 	if err != nil {
 		return err
 	}
-	err = clientcmd.WriteToFile(*updateConfig, file)
-	if err != nil {
+	_, err = ModifyConfig(file, change)
+	if err != nil || dryRun {
 		return err
 	}
 	printString(os.Stdout, "Update Config: "+file+"\n")
 	return nil
 }
 
+// ModifyConfig is the single write path of kubeconfig files. It takes a lock on the file,
+// re-reads it, applies change, backs up the previous content and writes it atomically.
+// With --dry-run the diff is printed and nothing is written.
+func ModifyConfig(file string, change func(config *clientcmdapi.Config) error) (*clientcmdapi.Config, error) {
+	unlock, err := utils.LockFile(lockPath(file))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	config, err := loadConfigFile(file)
+	if err != nil {
+		return nil, err
+	}
+	oldConfig := config.DeepCopy()
+	err = change(config)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		printYellow(os.Stdout, fmt.Sprintf("Dry run, 「%s」 is not changed:\n", file))
+		if !printConfigDiff(os.Stdout, oldConfig, config) {
+			fmt.Println("No changes.")
+		}
+		return config, nil
+	}
+	err = backupConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	return config, writeConfigFile(config, file)
+}
+
+// loadConfigFile load the kubeconfig file, an empty config when it does not exist yet
+func loadConfigFile(file string) (*clientcmdapi.Config, error) {
+	config, err := clientcmd.LoadFromFile(file)
+	if os.IsNotExist(err) {
+		return clientcmdapi.NewConfig(), nil
+	}
+	return config, err
+}
+
+// writeConfigFile serialize config and write it atomically to file
+func writeConfigFile(config *clientcmdapi.Config, file string) error {
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(file, content, 0600)
+}
+
+// lockPath return the path of the lock file guarding the kubeconfig file, a symlink and its target
+// share the lock
+func lockPath(file string) string {
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	return filepath.Join(kubecmHome(), "lock", pathHash(file)+".lock")
+}
+
+// pathHash return a short hash of the absolute path of file
+func pathHash(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+	return HashSufString(abs)
+}
+
 // ExitOption exit option of SelectUI
 func ExitOption(kubeItems []Needle) ([]Needle, error) {
 	u, err := user.Current()
@@ -471,29 +755,54 @@ func compareKubeItems(a, b Needle) int {
 
 // CheckValidContext check and clean mismatched AuthInfo and Cluster
 func CheckValidContext(clear bool, config *clientcmdapi.Config) *clientcmdapi.Config {
+	// collect first, a cluster or user is only removed when no remaining context uses it
+	var lapsed []string
 	for key, obj := range config.Contexts {
-		if _, ok := config.AuthInfos[obj.AuthInfo]; !ok {
+		_, userOK := config.AuthInfos[obj.AuthInfo]
+		_, clusterOK := config.Clusters[obj.Cluster]
+		if userOK && clusterOK {
+			continue
+		}
+		lapsed = append(lapsed, key)
+		if !userOK {
 			if clear {
 				printString(os.Stdout, fmt.Sprintf("clear lapsed AuthInfo [%s]\n", obj.AuthInfo))
 			} else {
 				printYellow(os.Stdout, fmt.Sprintf("WARNING: AuthInfo 「%s」 has no matching context 「%s」, please run `kubecm clear` to clean up this Context.\n", obj.AuthInfo, key))
 			}
-			delete(config.Contexts, key)
-			delete(config.Clusters, obj.Cluster)
 		}
-		if _, ok := config.Clusters[obj.Cluster]; !ok {
+		if !clusterOK {
 			if clear {
 				printString(os.Stdout, fmt.Sprintf("clear lapsed Cluster [%s]\n", obj.Cluster))
 			} else {
 				printYellow(os.Stdout, fmt.Sprintf("WARNING: Cluster 「%s」 has no matching context 「%s」, please run `kubecm clear` to clean up this Context.\n", obj.Cluster, key))
 			}
-			delete(config.Contexts, key)
+		}
+	}
+	sort.Strings(lapsed)
+	for _, key := range lapsed {
+		obj := config.Contexts[key]
+		delete(config.Contexts, key)
+		if !contextUses(config, obj.Cluster, "") {
+			delete(config.Clusters, obj.Cluster)
+		}
+		if !contextUses(config, "", obj.AuthInfo) {
 			delete(config.AuthInfos, obj.AuthInfo)
 		}
 	}
 	return config
 }
 
+// contextUses report whether a context of config uses the cluster or the user, empty names are ignored
+func contextUses(config *clientcmdapi.Config, cluster, user string) bool {
+	for _, obj := range config.Contexts {
+		if (cluster != "" && obj.Cluster == cluster) || (user != "" && obj.AuthInfo == user) {
+			return true
+		}
+	}
+	return false
+}
+
 func getFileName(path string) string {
 	n := strings.Split(path, "/")
 	result := strings.Split(n[len(n)-1], ".")
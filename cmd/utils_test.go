@@ -2,25 +2,31 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 
 	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
@@ -309,6 +315,14 @@ func TestCheckValidContext(t *testing.T) {
 		{"check-root", args{clear: false, config: &wrongRootConfig}, &appendConfigAlfa},
 		{"check-federal", args{clear: false, config: &wrongFederalConfig}, &appendRootConfigConflictAlfa},
 		{"clear-federal", args{clear: true, config: clearWrongConfig}, clearWrongWant},
+		{"keep-shared-cluster", args{clear: true, config: &clientcmdapi.Config{
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{"black-user": {Token: "black-token"}},
+			Clusters:  map[string]*clientcmdapi.Cluster{"pig-cluster": {Server: "http://pig.org:8080"}},
+			Contexts: map[string]*clientcmdapi.Context{
+				"root-context":    {AuthInfo: "black-user", Cluster: "pig-cluster", Namespace: "saw-ns"},
+				"federal-context": {AuthInfo: "red-user", Cluster: "pig-cluster", Namespace: "hammer-ns"},
+			},
+		}}, &appendRootConfigConflictAlfa},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -377,6 +391,131 @@ func TestMoreInfo(t *testing.T) {
 	}
 }
 
+// newPagingClientSet return a fake clientset holding pods pods, listed page by page like the API server,
+// with the remaining item count when remainingCount. The pages are sliced from the pods built once, so
+// a list allocates only the items of its page.
+func newPagingClientSet(pods int, remainingCount bool) *fake.Clientset {
+	items := make([]corev1.Pod, 0, pods)
+	for i := 0; i < pods; i++ {
+		items = append(items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default",
+				Labels: map[string]string{"app": "bench", "pod-template-hash": "5d8f7c9b6d"}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "app",
+				Image: "registry.example.org/team/app:v1.2.3",
+				Args:  []string{"--listen=:8080", "--log-level=info", "--config=/etc/app/config.yaml"},
+				Env:   []corev1.EnvVar{{Name: "POD_NAME", Value: fmt.Sprintf("pod-%d", i)}, {Name: "REGION", Value: "eu-west-1"}},
+			}}},
+		})
+	}
+	clientSet := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	clientSet.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.ListActionImpl).ListOptions
+		start, _ := strconv.Atoi(opts.Continue)
+		end := len(items)
+		if opts.Limit > 0 && start+int(opts.Limit) < end {
+			end = start + int(opts.Limit)
+		}
+		list := &corev1.PodList{Items: items[start:end:end]}
+		if end < len(items) {
+			list.Continue = strconv.Itoa(end)
+			if remainingCount {
+				remaining := int64(len(items) - end)
+				list.RemainingItemCount = &remaining
+			}
+		}
+		return true, list, nil
+	})
+	return clientSet
+}
+
+// maxPageList wrap the pod list of the clientset, recording the most items returned by one list
+func maxPageList(clientSet *fake.Clientset, maxItems *int) listFunc {
+	return func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		list, err := clientSet.CoreV1().Pods("").List(ctx, opts)
+		if err == nil && len(list.Items) > *maxItems {
+			*maxItems = len(list.Items)
+		}
+		return list, err
+	}
+}
+
+func Test_countItems(t *testing.T) {
+	for _, remainingCount := range []bool{true, false} {
+		var maxItems int
+		count, err := countItems(context.TODO(), maxPageList(newPagingClientSet(1234, remainingCount), &maxItems))
+		if err != nil || count != 1234 {
+			t.Fatalf("countItems() = %d, error = %v", count, err)
+		}
+		// the pods held at once are bounded by the page size
+		if want := map[bool]int{true: 1, false: summaryPageSize}[remainingCount]; maxItems != want {
+			t.Errorf("countItems() remainingCount = %v held %d pods at once, want %d", remainingCount, maxItems, want)
+		}
+	}
+}
+
+func Test_clusterSummary(t *testing.T) {
+	for _, remainingCount := range []bool{true, false} {
+		clientSet := newPagingClientSet(1234, remainingCount)
+		kv, err := clusterSummary(clientSet)
+		if err != nil {
+			t.Fatalf("clusterSummary() error = %v", err)
+		}
+		want := map[string]int{"Namespace": 1, "Node": 1, "Pod": 1234}
+		if !reflect.DeepEqual(kv, want) {
+			t.Errorf("clusterSummary() remainingCount = %v got = %v, want %v", remainingCount, kv, want)
+		}
+		var lists int
+		for _, action := range clientSet.Actions() {
+			if action.GetVerb() == "list" && action.GetResource().Resource == "pods" {
+				lists++
+			}
+		}
+		// one item with the remaining count, or 1 + 3 pages of 500
+		if wantLists := map[bool]int{true: 1, false: 4}[remainingCount]; lists != wantLists {
+			t.Errorf("clusterSummary() remainingCount = %v listed pods %d times, want %d", remainingCount, lists, wantLists)
+		}
+	}
+}
+
+// BenchmarkListAllPods counts the pods like MoreInfo did before, by listing all of them at once
+func BenchmarkListAllPods(b *testing.B) {
+	clientSet := newPagingClientSet(5000, true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pods, err := clientSet.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+		if err != nil || len(pods.Items) != 5000 {
+			b.Fatalf("List() error = %v", err)
+		}
+	}
+	b.ReportMetric(5000, "max-pods/op")
+}
+
+func BenchmarkCountPodsRemainingItemCount(b *testing.B) {
+	benchmarkCountPods(b, true)
+}
+
+func BenchmarkCountPodsPaginated(b *testing.B) {
+	benchmarkCountPods(b, false)
+}
+
+// benchmarkCountPods reports the most pods held at once besides the allocations of counting them
+func benchmarkCountPods(b *testing.B, remainingCount bool) {
+	var maxItems int
+	list := maxPageList(newPagingClientSet(5000, remainingCount), &maxItems)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		count, err := countItems(context.TODO(), list)
+		if err != nil || count != 5000 {
+			b.Fatalf("countItems() = %d, error = %v", count, err)
+		}
+	}
+	b.ReportMetric(float64(maxItems), "max-pods/op")
+}
+
 type testSelectPrompt struct {
 	index int
 	err   error
@@ -523,3 +662,147 @@ func TestValidateContextTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestModifyConfigConcurrent(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://cluster"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	const workers = 20
+	for i := 0; i < workers; i++ {
+		config.Contexts[fmt.Sprintf("context-%d", i)] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	}
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(file, content, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*2)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		name := fmt.Sprintf("context-%d", i)
+		go func() {
+			defer wg.Done()
+			_, err := ModifyConfig(file, func(config *clientcmdapi.Config) error {
+				return setNamespace(config, name, "ns-"+name)
+			})
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := ModifyConfig(file, func(config *clientcmdapi.Config) error {
+				_, err := handleQuickSwitch(config, name)
+				return err
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ModifyConfig() error = %v", err)
+		}
+	}
+
+	got, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	for name, ctx := range got.Contexts {
+		if ctx.Namespace != "ns-"+name {
+			t.Errorf("context %s lost its namespace update, got %q", name, ctx.Namespace)
+		}
+	}
+	if _, ok := got.Contexts[got.CurrentContext]; !ok {
+		t.Errorf("unexpected current-context %q", got.CurrentContext)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("file mode got %v, want %v", info.Mode().Perm(), os.FileMode(0640))
+	}
+}
+
+func TestModifyConfigError(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(appendMergeConfig, file); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(file)
+	_, err := ModifyConfig(file, func(config *clientcmdapi.Config) error {
+		delete(config.Contexts, "root-context")
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Fatal("ModifyConfig() want error")
+	}
+	after, _ := os.ReadFile(file)
+	if !bytes.Equal(before, after) {
+		t.Errorf("ModifyConfig() changed the file although the change failed")
+	}
+}
+
+func TestWriteConfigKeepsConcurrentChanges(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	oldCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = oldCfgFile })
+	cfgFile = filepath.Join(t.TempDir(), "config")
+	base := appendMergeConfig.DeepCopy()
+	if err := clientcmd.WriteToFile(*base, cfgFile); err != nil {
+		t.Fatal(err)
+	}
+	out := base.DeepCopy()
+	delete(out.Contexts, "root-context")
+	out.Contexts["federal-context"].Namespace = "restored"
+	out.CurrentContext = "federal-context"
+
+	// another process adds a context after the base was read
+	_, err := ModifyConfig(cfgFile, func(config *clientcmdapi.Config) error {
+		config.Contexts["new-context"] = &clientcmdapi.Context{Cluster: "pig-cluster", AuthInfo: "black-user"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = WriteConfig(true, cfgFile, base, out); err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+	got, err := clientcmd.LoadFromFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.Contexts["new-context"]; !ok {
+		t.Errorf("WriteConfig() discarded the context written meanwhile")
+	}
+	if _, ok := got.Contexts["root-context"]; ok {
+		t.Errorf("WriteConfig() kept the removed context")
+	}
+	if got.Contexts["federal-context"].Namespace != "restored" || got.CurrentContext != "federal-context" {
+		t.Errorf("WriteConfig() got %+v, current %s", got.Contexts["federal-context"], got.CurrentContext)
+	}
+}
+
+func Test_lockPath(t *testing.T) {
+	t.Setenv("KUBECM_HOME", t.TempDir())
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(file, link); err != nil {
+		t.Skip(err)
+	}
+	if lockPath(link) != lockPath(file) {
+		t.Errorf("lockPath() of a symlink %s, want the lock of its target %s", lockPath(link), lockPath(file))
+	}
+}
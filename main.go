@@ -29,6 +29,6 @@ func main() {
 	baseCommand := cmd.NewBaseCommand()
 	if err := baseCommand.CobraCmd().Execute(); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
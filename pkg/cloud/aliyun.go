@@ -14,6 +14,24 @@ type AliCloud struct {
 	AccessKeySecret string
 }
 
+var aliCloudProvider = &Provider{
+	Name:     "AlibabaCloud",
+	Alias:    []string{"alibabacloud", "alicloud", "aliyun", "ack"},
+	HomePage: "https://cs.console.aliyun.com",
+	Service:  "ACK",
+	Credentials: []Credential{
+		{Env: "ACCESS_KEY_ID", Prompt: "AlibabaCloud Access Key ID"},
+		{Env: "ACCESS_KEY_SECRET", Prompt: "AlibabaCloud Access Key Secret"},
+	},
+	ContextPrefix: "alicloud",
+	New: func(s *Session) (Cluster, error) {
+		return &AliCloud{
+			AccessKeyID:     s.Credentials["ACCESS_KEY_ID"],
+			AccessKeySecret: s.Credentials["ACCESS_KEY_SECRET"],
+		}, nil
+	},
+}
+
 // getClient get aliyun openapi client
 func getClient(accessKeyID, accessKeySecret string) (*ack.Client, error) {
 	config := &openapi.Config{
@@ -2,34 +2,141 @@ package cloud
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
-
-	"github.com/aws/aws-sdk-go/service/sts"
-	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
-
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
-	"github.com/aws/aws-sdk-go/service/eks"
-
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// awsDefaultRegion is the region the enabled regions are asked in when no region is configured
+const awsDefaultRegion = "us-east-1"
+
 // AWS struct of aws cloud
 type AWS struct {
+	// AccessKeyID and AccessKeySecret are static credentials, the shared config credential chain
+	// (env, profile, SSO, instance role) is used when they are empty
 	AccessKeyID     string
 	AccessKeySecret string
-	RegionID        string
+	SessionToken    string
+	// RegionID limits the clusters to one region, all the enabled regions are listed when empty
+	RegionID string
+	// Profile is the profile of the shared config to authenticate with
+	Profile string
+	// RoleARN is the role assumed with the credentials, with the ExternalID when set
+	RoleARN    string
+	ExternalID string
+
+	// endpoint replaces the endpoints of all the services, used by the tests
+	endpoint string
 }
 
-// getSession get session of aws cloud
-func (a *AWS) getSession() (*session.Session, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(a.RegionID),
-		Credentials: credentials.NewStaticCredentials(a.AccessKeyID, a.AccessKeySecret, ""),
+// awsKeys the static keys of aws, asked for when the credential chain has none
+var awsKeys = []Credential{
+	{Env: "AWS_ACCESS_KEY_ID", Prompt: "AWS Access Key ID", Optional: true},
+	{Env: "AWS_SECRET_ACCESS_KEY", Prompt: "AWS Access Key Secret", Optional: true},
+}
+
+var awsProvider = &Provider{
+	Name:        "AWS",
+	Alias:       []string{"aws", "eks"},
+	HomePage:    "https://console.aws.amazon.com/eks/home",
+	Service:     "EKS",
+	Credentials: append(awsKeys[:len(awsKeys):len(awsKeys)], Credential{Env: "AWS_SESSION_TOKEN", Optional: true}),
+	ContextName: func(cluster ClusterInfo) string {
+		name := cluster.Name
+		if name == "" {
+			_, name = parseEKSClusterID(cluster.ID)
+		}
+		return fmt.Sprintf("aws-%s", name)
+	},
+	ParseClusterID: func(clusterID, regionID string) (ClusterInfo, error) {
+		if id, name := parseEKSClusterID(clusterID); id != "" {
+			regionID = id
+			clusterID = name
+		}
+		if regionID == "" {
+			return ClusterInfo{}, &MissingInputError{Flags: []string{"region_id"}}
+		}
+		return ClusterInfo{ID: regionID + "/" + clusterID, Name: clusterID, RegionID: regionID}, nil
+	},
+	Note: "please install the AWS CLI before normal use.",
+	New: func(s *Session) (Cluster, error) {
+		a := &AWS{
+			AccessKeyID:     s.Credentials["AWS_ACCESS_KEY_ID"],
+			AccessKeySecret: s.Credentials["AWS_SECRET_ACCESS_KEY"],
+			SessionToken:    s.Credentials["AWS_SESSION_TOKEN"],
+			RegionID:        s.RegionID,
+			Profile:         s.Profile,
+			RoleARN:         s.RoleARN,
+			ExternalID:      s.ExternalID,
+		}
+		if a.AccessKeyID != "" || a.Profile != "" || s.Prompter == nil {
+			return a, nil
+		}
+		// ask for the keys when the credential chain has none, they are kept in the session
+		// since the client is created again after the region is selected
+		sess, err := a.getSession("")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = sess.Config.Credentials.Get(); err == nil {
+			return a, nil
+		}
+		if s.Credentials == nil {
+			s.Credentials = make(map[string]string)
+		}
+		for _, cred := range awsKeys {
+			value, err := s.Prompter.Input(cred.Prompt)
+			if err != nil {
+				return nil, err
+			}
+			s.Credentials[cred.Env] = value
+		}
+		a.AccessKeyID = s.Credentials["AWS_ACCESS_KEY_ID"]
+		a.AccessKeySecret = s.Credentials["AWS_SECRET_ACCESS_KEY"]
+		return a, nil
+	},
+}
+
+// getSession get session of aws cloud in the region, the one of the shared config when empty, with the
+// static keys, or the profile and shared config credential chain, assuming the role when set
+func (a *AWS) getSession(regionID string) (*session.Session, error) {
+	opts := session.Options{
+		Profile:           a.Profile,
+		SharedConfigState: session.SharedConfigEnable,
+	}
+	if regionID != "" {
+		opts.Config.Region = aws.String(regionID)
+	}
+	if a.endpoint != "" {
+		opts.Config.Endpoint = aws.String(a.endpoint)
+	}
+	if a.AccessKeyID != "" && a.Profile == "" {
+		opts.Config.Credentials = credentials.NewStaticCredentials(a.AccessKeyID, a.AccessKeySecret, a.SessionToken)
+	}
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil || a.RoleARN == "" {
+		return sess, err
+	}
+	creds := stscreds.NewCredentials(sess, a.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if a.ExternalID != "" {
+			p.ExternalID = aws.String(a.ExternalID)
+		}
 	})
-	return sess, err
+	return sess.Copy(&aws.Config{Credentials: creds}), nil
 }
 
 // GetRegionID get region id of aws
@@ -45,117 +152,223 @@ func GetRegionID() ([]string, error) {
 	return regionList, nil
 }
 
-// ListCluster list cluster info of aws
-func (a *AWS) ListCluster() (clusters []ClusterInfo, err error) {
-	sess, err := a.getSession()
+// GetRegionID get the regions enabled for the account
+func (a *AWS) GetRegionID() ([]string, error) {
+	sess, err := a.getSession(a.RegionID)
 	if err != nil {
 		return nil, err
 	}
-	var clusterList []ClusterInfo
-	svc := eks.New(sess)
-	input := &eks.ListClustersInput{}
-
-	result, err := svc.ListClusters(input)
+	// the regions are asked in the region of the shared config, which is in the partition of the account
+	regionID := aws.StringValue(sess.Config.Region)
+	if regionID == "" {
+		regionID = awsDefaultRegion
+	}
+	result, err := ec2.New(sess, &aws.Config{Region: aws.String(regionID)}).DescribeRegions(&ec2.DescribeRegionsInput{})
 	if err != nil {
 		return nil, err
 	}
-
-	for _, clusterName := range result.Clusters {
-		clusterInfo, err := a.getClusterInfo(*clusterName)
-		if err != nil {
-			return nil, err
-		}
-		clusterList = append(clusterList, clusterInfo)
+	var regionList []string
+	for _, region := range result.Regions {
+		regionList = append(regionList, aws.StringValue(region.RegionName))
 	}
-	return clusterList, nil
+	sort.Strings(regionList)
+	return regionList, nil
+}
+
+// ListCluster list cluster info of aws, of all the enabled regions concurrently when RegionID is empty
+func (a *AWS) ListCluster() ([]ClusterInfo, error) {
+	clusters, _, err := a.ListClusterPartial()
+	return clusters, err
 }
 
-// GetClusterInfo get cluster info of aws eks
-func (a *AWS) getClusterInfo(clusterName string) (clusterInfo ClusterInfo, err error) {
-	sess, err := a.getSession()
+// ListClusterPartial list cluster info of aws as ListCluster, with the regions denied by a policy
+func (a *AWS) ListClusterPartial() (clusters []ClusterInfo, skipped []string, err error) {
+	regionList := []string{a.RegionID}
+	if a.RegionID == "" {
+		if regionList, err = a.GetRegionID(); err != nil || len(regionList) == 0 {
+			return nil, nil, err
+		}
+	}
+	// one session is shared by the regions, the sessions are not safe to create concurrently and the
+	// role is assumed once
+	sess, err := a.getSession(regionList[0])
 	if err != nil {
-		return ClusterInfo{}, err
+		return nil, nil, err
 	}
-
-	svcSts := sts.New(sess)
-	callerIdentity, err := svcSts.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	callerIdentity, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
 	if err != nil {
-		return ClusterInfo{}, err
+		return nil, nil, err
 	}
+	account := aws.StringValue(callerIdentity.Account)
 
-	svc := eks.New(sess)
-	input := &eks.DescribeClusterInput{
-		Name: &clusterName,
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, regionID := range regionList {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			regionClusters, err := listRegionCluster(sess, regionID, account)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// a region denied by a policy is skipped when listing all of them
+				if a.RegionID == "" && isAWSAccessDenied(err) {
+					skipped = append(skipped, regionID)
+					return
+				}
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %v", regionID, err)
+				}
+				return
+			}
+			clusters = append(clusters, regionClusters...)
+		}()
 	}
-	cluster, err := svc.DescribeCluster(input)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ID < clusters[j].ID
+	})
+	sort.Strings(skipped)
+	return clusters, skipped, nil
+}
+
+// listRegionCluster list the eks clusters of the region with the session, following the NextToken of the pages
+func listRegionCluster(sess *session.Session, regionID, account string) ([]ClusterInfo, error) {
+	svc := eks.New(sess, &aws.Config{Region: aws.String(regionID)})
+	var names []string
+	err := svc.ListClustersPages(&eks.ListClustersInput{}, func(page *eks.ListClustersOutput, lastPage bool) bool {
+		names = append(names, aws.StringValueSlice(page.Clusters)...)
+		return true
+	})
 	if err != nil {
-		return ClusterInfo{}, err
+		return nil, err
+	}
+	var clusterList []ClusterInfo
+	for _, name := range names {
+		cluster, err := svc.DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(name)})
+		if err != nil {
+			return nil, err
+		}
+		clusterList = append(clusterList, ClusterInfo{
+			ID:         regionID + "/" + name,
+			Account:    account,
+			Name:       name,
+			RegionID:   regionID,
+			K8sVersion: aws.StringValue(cluster.Cluster.Version),
+			ConsoleURL: fmt.Sprintf("https://%s.console.aws.amazon.com/eks/home?region=%s#/clusters/%s", regionID, regionID, name),
+		})
+	}
+	return clusterList, nil
+}
+
+// isAWSAccessDenied report whether the error is an access denied by an iam or organization policy
+func isAWSAccessDenied(err error) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "AccessDeniedException", "AccessDenied", "UnrecognizedClientException":
+			return true
+		}
 	}
-	return ClusterInfo{
-		ID:         *cluster.Cluster.Name,
-		Account:    *callerIdentity.Account,
-		Name:       *cluster.Cluster.Name,
-		RegionID:   a.RegionID,
-		K8sVersion: *cluster.Cluster.Version,
-		ConsoleURL: fmt.Sprintf("https://%s.console.aws.amazon.com/eks/home?region=%s#/clusters/%s", a.RegionID, a.RegionID, *cluster.Cluster.Name),
-	}, err
+	return false
+}
 
+// parseEKSClusterID split the cluster id REGION/NAME, the region is empty for an id of just the name
+func parseEKSClusterID(clusterID string) (regionID, name string) {
+	if i := strings.LastIndex(clusterID, "/"); i >= 0 {
+		return clusterID[:i], clusterID[i+1:]
+	}
+	return "", clusterID
 }
 
-// GetKubeConfigObj get aws eks kubeConfig file
+// GetKubeConfigObj get aws eks kubeConfig file, the cluster id is REGION/NAME, or NAME in the region of RegionID
 func (a *AWS) GetKubeConfigObj(clusterID string) (*clientcmdapi.Config, error) {
-	sess, err := a.getSession()
+	regionID, name := parseEKSClusterID(clusterID)
+	if regionID == "" {
+		regionID = a.RegionID
+	}
+	if regionID == "" || name == "" {
+		return nil, fmt.Errorf("invalid eks cluster id %q, the format is REGION/NAME, or NAME with --region_id", clusterID)
+	}
+	// aws eks get-token has no external id, the kubeconfig would fail to get a token
+	if a.ExternalID != "" {
+		return nil, errors.New("aws eks get-token can not pass an external id, put role_arn and external_id " +
+			"in a profile of the shared config and use it with --profile instead of --role-arn and --external-id")
+	}
+	sess, err := a.getSession(regionID)
 	if err != nil {
 		return nil, err
 	}
 
 	svc := eks.New(sess)
 	input := &eks.DescribeClusterInput{
-		Name: &clusterID,
+		Name: aws.String(name),
 	}
 	cluster, err := svc.DescribeCluster(input)
 	if err != nil {
 		return nil, err
 	}
 
-	decodePem, err := base64.StdEncoding.DecodeString(*cluster.Cluster.CertificateAuthority.Data)
+	decodePem, err := base64.StdEncoding.DecodeString(aws.StringValue(cluster.Cluster.CertificateAuthority.Data))
 	if err != nil {
 		return nil, err
 	}
 
+	args := []string{
+		"eks",
+		"get-token",
+		"--cluster-name",
+		name,
+		"--region",
+		regionID,
+		"--output",
+		"json",
+	}
+	if a.Profile != "" {
+		args = append(args, "--profile", a.Profile)
+	}
+	if a.RoleARN != "" {
+		args = append(args, "--role-arn", a.RoleARN)
+	}
 	kubeconfig := &clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{
-			*cluster.Cluster.Name: {
-				Server:                   *cluster.Cluster.Endpoint,
+			name: {
+				Server:                   aws.StringValue(cluster.Cluster.Endpoint),
 				CertificateAuthorityData: decodePem,
 			},
 		},
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{
-			*cluster.Cluster.Name: {
+			name: {
 				Exec: &clientcmdapi.ExecConfig{
 					APIVersion: "client.authentication.k8s.io/v1beta1",
 					Command:    "aws",
-					Args: []string{
-						"eks",
-						"get-token",
-						"--cluster-name",
-						*cluster.Cluster.Name,
-						"--region",
-						a.RegionID,
-						"--output",
-						"json",
-					},
+					Args:       args,
 				},
 			},
 		},
 		Contexts: map[string]*clientcmdapi.Context{
-			*cluster.Cluster.Name: {
-				Cluster:  *cluster.Cluster.Name,
-				AuthInfo: *cluster.Cluster.Name,
+			name: {
+				Cluster:  name,
+				AuthInfo: name,
 			},
 		},
-		CurrentContext: *cluster.Cluster.Name,
+		CurrentContext: name,
 	}
 
 	return kubeconfig, nil
 }
+
+// GetKubeConfig get aws eks kubeConfig file
+func (a *AWS) GetKubeConfig(clusterID string) (string, error) {
+	config, err := a.GetKubeConfigObj(clusterID)
+	if err != nil {
+		return "", err
+	}
+	content, err := clientcmd.Write(*config)
+	return string(content), err
+}
@@ -1,6 +1,19 @@
 package cloud
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -27,3 +40,220 @@ func TestGetRegionID(t *testing.T) {
 		})
 	}
 }
+
+// awsRequestRegion return the region of the credential scope of the signed request
+func awsRequestRegion(r *http.Request) (accessKeyID, regionID string) {
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "Credential=")
+	if i < 0 {
+		return "", ""
+	}
+	scope := strings.Split(strings.SplitN(auth[i+len("Credential="):], ",", 2)[0], "/")
+	if len(scope) < 3 {
+		return "", ""
+	}
+	return scope[0], scope[2]
+}
+
+// newFakeAWS start a fake of sts, ec2 and eks with the clusters of each region, denied is a region
+// the eks api is denied in, the clusters are listed one per page
+func newFakeAWS(t *testing.T, clusters map[string][]string, denied string) (*AWS, *sync.Map) {
+	var calls sync.Map
+	ca := base64.StdEncoding.EncodeToString([]byte("fake-ca"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessKeyID, regionID := awsRequestRegion(r)
+		if accessKeyID != "aws-ak" && accessKeyID != "assumed-ak" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<ErrorResponse><Error><Code>InvalidClientTokenId</Code><Message>invalid</Message></Error></ErrorResponse>`)
+			return
+		}
+		if r.Method == http.MethodPost {
+			_ = r.ParseForm()
+			action := r.PostForm.Get("Action")
+			calls.Store(action, r.PostForm)
+			switch action {
+			case "AssumeRole":
+				fmt.Fprint(w, `<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>assumed-ak</AccessKeyId>
+<SecretAccessKey>assumed-sk</SecretAccessKey><SessionToken>token</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration>
+</Credentials></AssumeRoleResult></AssumeRoleResponse>`)
+			case "GetCallerIdentity":
+				fmt.Fprintf(w, `<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account>
+<Arn>arn:aws:iam::123456789012:user/%s</Arn></GetCallerIdentityResult></GetCallerIdentityResponse>`, accessKeyID)
+			case "DescribeRegions":
+				var items []string
+				for region := range clusters {
+					items = append(items, "<item><regionName>"+region+"</regionName></item>")
+				}
+				fmt.Fprintf(w, `<DescribeRegionsResponse><regionInfo>%s</regionInfo></DescribeRegionsResponse>`, strings.Join(items, ""))
+			}
+			return
+		}
+		calls.Store("eks "+regionID+" "+r.URL.String(), accessKeyID)
+		if regionID == denied {
+			w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"denied by a service control policy"}`)
+			return
+		}
+		names := clusters[regionID]
+		if r.URL.Path == "/clusters" {
+			i := 0
+			if token := r.URL.Query().Get("nextToken"); token != "" {
+				i, _ = strconv.Atoi(token)
+			}
+			page := map[string]interface{}{"clusters": []string{}}
+			if i < len(names) {
+				page["clusters"] = names[i : i+1]
+			}
+			if i+1 < len(names) {
+				page["nextToken"] = strconv.Itoa(i + 1)
+			}
+			_ = json.NewEncoder(w).Encode(page)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/clusters/")
+		fmt.Fprintf(w, `{"cluster":{"name":%q,"version":"1.29","endpoint":"https://%s.%s.eks.amazonaws.com","certificateAuthority":{"data":%q}}}`,
+			name, name, regionID, ca)
+	}))
+	t.Cleanup(server.Close)
+	// the shared config of the machine running the tests is not read
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	return &AWS{AccessKeyID: "aws-ak", AccessKeySecret: "aws-sk", endpoint: server.URL}, &calls
+}
+
+func TestAWS_ListCluster(t *testing.T) {
+	a, calls := newFakeAWS(t, map[string][]string{
+		"us-east-1":    {"web", "jobs", "batch"},
+		"eu-west-1":    {"api"},
+		"ap-south-1":   nil,
+		"me-central-1": {"secret"},
+	}, "me-central-1")
+	clusters, err := a.ListCluster()
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	var ids []string
+	for _, cluster := range clusters {
+		ids = append(ids, cluster.ID)
+	}
+	want := []string{"eu-west-1/api", "us-east-1/batch", "us-east-1/jobs", "us-east-1/web"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ListCluster() got %v, want %v", ids, want)
+	}
+	if got := clusters[0]; got.Account != "123456789012" || got.Name != "api" || got.RegionID != "eu-west-1" || got.K8sVersion != "1.29" {
+		t.Errorf("ListCluster() got = %+v", got)
+	}
+	if _, skipped, err := ListClusters(a); err != nil || !reflect.DeepEqual(skipped, []string{"me-central-1"}) {
+		t.Errorf("ListClusters() skipped %v, error = %v, want me-central-1", skipped, err)
+	}
+	// the clusters of us-east-1 are listed one per page
+	if _, ok := calls.Load("eks us-east-1 /clusters?nextToken=2"); !ok {
+		t.Errorf("ListCluster() did not follow the nextToken of the pages")
+	}
+
+	// a denied region fails when it is the one asked for
+	a.RegionID = "me-central-1"
+	if _, err = a.ListCluster(); err == nil || !strings.Contains(err.Error(), "me-central-1") {
+		t.Errorf("ListCluster() of a denied region error = %v", err)
+	}
+	a.RegionID = "eu-west-1"
+	if clusters, err = a.ListCluster(); err != nil || len(clusters) != 1 {
+		t.Errorf("ListCluster() of eu-west-1 got %v, error = %v", clusters, err)
+	}
+
+	a.AccessKeyID = "wrong"
+	if _, err = a.ListCluster(); err == nil || !strings.Contains(err.Error(), "InvalidClientTokenId") {
+		t.Errorf("ListCluster() with a wrong key error = %v", err)
+	}
+}
+
+func TestAWS_assumeRole(t *testing.T) {
+	a, calls := newFakeAWS(t, map[string][]string{"eu-west-1": {"api"}}, "")
+	a.RegionID = "eu-west-1"
+	a.RoleARN = "arn:aws:iam::123456789012:role/kubecm"
+	a.ExternalID = "external"
+	if _, err := a.ListCluster(); err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	form, ok := calls.Load("AssumeRole")
+	if !ok {
+		t.Fatalf("ListCluster() did not assume the role")
+	}
+	if got := form.(url.Values); got.Get("RoleArn") != a.RoleARN || got.Get("ExternalId") != "external" {
+		t.Errorf("AssumeRole got %v", got)
+	}
+	if key, _ := calls.Load("eks eu-west-1 /clusters"); key != "assumed-ak" {
+		t.Errorf("ListCluster() signed with %v, want the assumed role", key)
+	}
+}
+
+func TestAWS_GetKubeConfigObj(t *testing.T) {
+	a, _ := newFakeAWS(t, map[string][]string{"eu-west-1": {"api"}}, "")
+	a.Profile = "prod"
+	a.RoleARN = "arn:aws:iam::123456789012:role/kubecm"
+	// the keys of the profile, the static ones are ignored with a profile
+	err := os.WriteFile(os.Getenv("AWS_SHARED_CREDENTIALS_FILE"),
+		[]byte("[prod]\naws_access_key_id = aws-ak\naws_secret_access_key = aws-sk\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.AccessKeyID = "ignored"
+
+	config, err := a.GetKubeConfigObj("eu-west-1/api")
+	if err != nil {
+		t.Fatalf("GetKubeConfigObj() error = %v", err)
+	}
+	if got := config.Clusters["api"]; got.Server != "https://api.eu-west-1.eks.amazonaws.com" || string(got.CertificateAuthorityData) != "fake-ca" {
+		t.Errorf("GetKubeConfigObj() got cluster %+v", got)
+	}
+	want := []string{"eks", "get-token", "--cluster-name", "api", "--region", "eu-west-1", "--output", "json",
+		"--profile", "prod", "--role-arn", "arn:aws:iam::123456789012:role/kubecm"}
+	if got := config.AuthInfos["api"].Exec.Args; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetKubeConfigObj() got exec args %v, want %v", got, want)
+	}
+
+	if _, err = a.GetKubeConfigObj("api"); err == nil {
+		t.Errorf("GetKubeConfigObj() of a name without region should fail")
+	}
+	a.RegionID = "eu-west-1"
+	if config, err = a.GetKubeConfigObj("api"); err != nil || config.CurrentContext != "api" {
+		t.Errorf("GetKubeConfigObj() of a name with RegionID got %v, error = %v", config, err)
+	}
+	// the exec config can not pass the external id
+	a.ExternalID = "external"
+	if _, err = a.GetKubeConfigObj("eu-west-1/api"); err == nil || !strings.Contains(err.Error(), "--profile") {
+		t.Errorf("GetKubeConfigObj() with an external id error = %v, want the profile hint", err)
+	}
+}
+
+func Test_parseEKSClusterID(t *testing.T) {
+	tests := []struct {
+		id, regionID, name string
+	}{
+		{"us-east-1/web", "us-east-1", "web"},
+		{"web", "", "web"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		regionID, name := parseEKSClusterID(tt.id)
+		if regionID != tt.regionID || name != tt.name {
+			t.Errorf("parseEKSClusterID(%q) = %q, %q, want %q, %q", tt.id, regionID, name, tt.regionID, tt.name)
+		}
+	}
+	if got := awsProvider.ContextName(ClusterInfo{ID: "us-east-1/web"}); got != "aws-web" {
+		t.Errorf("ContextName() = %s, want aws-web", got)
+	}
+
+	want := ClusterInfo{ID: "us-east-1/web", Name: "web", RegionID: "us-east-1"}
+	for _, id := range []string{"us-east-1/web", "web"} {
+		if got, err := awsProvider.ClusterByID(id, "us-east-1"); err != nil || got != want {
+			t.Errorf("ClusterByID(%q) = %+v, %v, want %+v", id, got, err, want)
+		}
+	}
+	var missing *MissingInputError
+	if _, err := awsProvider.ClusterByID("web", ""); !errors.As(err, &missing) {
+		t.Errorf("ClusterByID() of a name without region error = %v, want a missing region_id", err)
+	}
+}
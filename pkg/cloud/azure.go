@@ -3,6 +3,9 @@ package cloud
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/subscription/armsubscription"
 
@@ -11,6 +14,74 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v4"
 )
 
+// azure credentials resolved when the auth mode needs them
+var (
+	azureTenantCredential  = Credential{Env: "AZURE_TENANT_ID", Prompt: "Azure Tenant ID"}
+	azureSecretCredentials = []Credential{
+		{Env: "AZURE_CLIENT_ID", Prompt: "Azure Client ID"},
+		{Env: "AZURE_CLIENT_SECRET", Prompt: "Azure Client Secret"},
+	}
+	azureObjectCredential = Credential{Env: "AZURE_OBJECT_ID", Prompt: "Azure Object ID"}
+)
+
+var azureProvider = &Provider{
+	Name:     "Azure",
+	Alias:    []string{"azure", "aks"},
+	HomePage: "https://portal.azure.com",
+	Service:  "AKS",
+	Credentials: []Credential{
+		{Env: "AZURE_SUBSCRIPTION_ID", Optional: true},
+	},
+	ContextPrefix: "azure",
+	// the clusters are added under their name, the last segment of the resource id when added by
+	// --cluster_id. A cluster picked interactively used to be added as "azure-", the same name for
+	// every cluster.
+	ContextName: func(cluster ClusterInfo) string {
+		if cluster.Name != "" {
+			return cluster.Name
+		}
+		return cluster.ID[strings.LastIndex(cluster.ID, "/")+1:]
+	},
+	New: func(s *Session) (Cluster, error) {
+		authMode, err := azureAuthMode(s)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.Resolve(azureTenantCredential); err != nil {
+			return nil, err
+		}
+		azure := &Azure{
+			AuthMode:       AzureAuth(authMode),
+			TenantID:       s.Credentials[azureTenantCredential.Env],
+			SubscriptionID: s.Credentials["AZURE_SUBSCRIPTION_ID"],
+		}
+		if azure.AuthMode == AuthModeServicePrincipal {
+			if err = s.Resolve(azureSecretCredentials...); err != nil {
+				return nil, err
+			}
+			if err = s.Resolve(azureObjectCredential); err != nil {
+				return nil, err
+			}
+			azure.ClientID = s.Credentials["AZURE_CLIENT_ID"]
+			azure.ClientSecret = s.Credentials["AZURE_CLIENT_SECRET"]
+			azure.ObjectID = s.Credentials[azureObjectCredential.Env]
+		}
+		return &azureCluster{Azure: azure, prompter: s.Prompter}, nil
+	},
+}
+
+// azureAuthMode return the selected auth mode, or in non-interactive mode the service principal
+// when its client id is set and the SDK auth otherwise
+func azureAuthMode(s *Session) (int, error) {
+	if s.Prompter == nil {
+		if _, ok := os.LookupEnv(azureSecretCredentials[0].Env); ok {
+			return int(AuthModeServicePrincipal), nil
+		}
+		return int(AuthModeDefault), nil
+	}
+	return s.Prompter.Select("Select Auth Type", []string{"Default (SDK Auth)", "Service Principal"})
+}
+
 // Azure struct of azure cloud
 type Azure struct {
 	AuthMode       AzureAuth
@@ -20,6 +91,8 @@ type Azure struct {
 	TenantID       string
 	ObjectID       string
 
+	// mu guards the client created on first use
+	mu     sync.Mutex
 	client azcore.TokenCredential
 }
 type AzureAuth int
@@ -35,6 +108,8 @@ const (
 )
 
 func (a *Azure) getAzureClient() (azcore.TokenCredential, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	if a.client != nil {
 		return a.client, nil
 	}
@@ -131,48 +206,122 @@ func (a *Azure) ListCluster(subscription AzureSubscription) (clusters []ClusterI
 
 // GetKubeConfig get kubeConfig file
 func (a *Azure) GetKubeConfig(clusterName, resourceGroupName string) ([]byte, error) {
+	return a.getKubeConfig(a.SubscriptionID, resourceGroupName, clusterName, false)
+}
+
+// GetAdminKubeConfig get kubeConfig file
+func (a *Azure) GetAdminKubeConfig(clusterName, resourceGroupName string) ([]byte, error) {
+	return a.getKubeConfig(a.SubscriptionID, resourceGroupName, clusterName, true)
+}
+
+// getKubeConfig get the user or admin kubeConfig of the cluster of the subscription
+func (a *Azure) getKubeConfig(subscriptionID, resourceGroupName, clusterName string, admin bool) ([]byte, error) {
 	client, err := a.getAzureClient()
 	if err != nil {
 		return nil, err
 	}
 
-	aksClient, err := armcontainerservice.NewManagedClustersClient(a.SubscriptionID, client, nil)
+	aksClient, err := armcontainerservice.NewManagedClustersClient(subscriptionID, client, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	res, err := aksClient.ListClusterUserCredentials(context.Background(), resourceGroupName, clusterName, nil)
-	if err != nil {
-		return nil, err
+	var kubeconfig []*armcontainerservice.CredentialResult
+	if admin {
+		res, err := aksClient.ListClusterAdminCredentials(context.Background(), resourceGroupName, clusterName, nil)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfig = res.Kubeconfigs
+	} else {
+		res, err := aksClient.ListClusterUserCredentials(context.Background(), resourceGroupName, clusterName, nil)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfig = res.Kubeconfigs
 	}
-
-	kubeconfig := res.Kubeconfigs
 	for _, v := range kubeconfig {
 		return v.Value, nil
 	}
 	return nil, nil
 }
 
-// GetAdminKubeConfig get kubeConfig file
-func (a *Azure) GetAdminKubeConfig(clusterName, resourceGroupName string) ([]byte, error) {
-	client, err := a.getAzureClient()
+// azureCluster the Cluster of azure, listing the clusters of all the subscriptions
+// and asking for the type of the kubeconfig once
+type azureCluster struct {
+	*Azure
+	prompter Prompter
+
+	mu             sync.Mutex
+	kubeConfigType *int
+}
+
+// GetRegionID get region id of aks cluster
+func (a *azureCluster) GetRegionID() ([]string, error) {
+	// aks lists the clusters of all the locations at once, return nil
+	return nil, nil
+}
+
+// ListCluster list the aks clusters of the subscription, or of all the subscriptions
+func (a *azureCluster) ListCluster() (clusters []ClusterInfo, err error) {
+	subscriptionList, err := a.ListSubscriptions()
 	if err != nil {
 		return nil, err
 	}
+	for _, subscription := range subscriptionList {
+		if a.SubscriptionID != "" && a.SubscriptionID != subscription.ID {
+			continue
+		}
+		subscriptionClusters, err := a.Azure.ListCluster(subscription)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, subscriptionClusters...)
+	}
+	return clusters, nil
+}
 
-	aksClient, err := armcontainerservice.NewManagedClustersClient(a.SubscriptionID, client, nil)
-	if err != nil {
-		return nil, err
+// GetKubeConfig get the user or admin kubeConfig of the cluster of the resource id
+func (a *azureCluster) GetKubeConfig(clusterID string) (string, error) {
+	clusterIDParts := strings.Split(clusterID, "/")
+	if len(clusterIDParts) != 9 {
+		return "", fmt.Errorf("invalid id %s", clusterID)
 	}
+	subscriptionID := clusterIDParts[2]
+	resourceGroup := clusterIDParts[4]
+	clusterName := clusterIDParts[8]
 
-	res, err := aksClient.ListClusterAdminCredentials(context.Background(), resourceGroupName, clusterName, nil)
+	kubeConfigType, err := a.getKubeConfigType()
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	var kubeConfig []byte
+	switch kubeConfigType {
+	case 0:
+		kubeConfig, err = a.getKubeConfig(subscriptionID, resourceGroup, clusterName, false)
+	case 1:
+		kubeConfig, err = a.getKubeConfig(subscriptionID, resourceGroup, clusterName, true)
+	default:
+		return "", fmt.Errorf("invalid config type %d", kubeConfigType)
 	}
+	return string(kubeConfig), err
+}
 
-	kubeconfig := res.Kubeconfigs
-	for _, v := range kubeconfig {
-		return v.Value, nil
+// getKubeConfigType ask for the type of the kubeconfig on the first call, the concurrent calls wait
+// for the answer. The user config is used in non-interactive mode
+func (a *azureCluster) getKubeConfigType() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.kubeConfigType != nil {
+		return *a.kubeConfigType, nil
 	}
-	return nil, nil
+	kubeConfigType := 0
+	if a.prompter != nil {
+		var err error
+		if kubeConfigType, err = a.prompter.Select("Select Config Type", []string{"User Config", "Admin Config"}); err != nil {
+			return 0, err
+		}
+	}
+	a.kubeConfigType = &kubeConfigType
+	return kubeConfigType, nil
 }
@@ -0,0 +1,30 @@
+package cloud
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestAzureCluster_getKubeConfigType(t *testing.T) {
+	prompter := &testPrompter{}
+	a := &azureCluster{Azure: &Azure{}, prompter: prompter}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := a.getKubeConfigType(); err != nil || got != 0 {
+				t.Errorf("getKubeConfigType() = %d, error = %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if want := []string{"Select Config Type"}; !reflect.DeepEqual(prompter.asked, want) {
+		t.Errorf("getKubeConfigType() asked %v, want %v", prompter.asked, want)
+	}
+
+	if _, err := a.GetKubeConfig("/subscriptions/s/resourceGroups/rg"); err == nil {
+		t.Errorf("GetKubeConfig() of an invalid id should fail")
+	}
+}
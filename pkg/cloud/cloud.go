@@ -1,18 +1,35 @@
 package cloud
 
-// Cluster interface of cloud k8s cluster
+// Cluster interface of cloud k8s cluster. Its methods must be safe for concurrent use, the
+// kubeconfigs of the clusters are fetched concurrently by kubecm cloud sync
 type Cluster interface {
 	GetRegionID() ([]string, error)
 	ListCluster() (clusters []ClusterInfo, err error)
 	GetKubeConfig(clusterID string) (kubeconfig string, err error)
 }
 
+// PartialLister is implemented by the clusters whose listing of all the regions or projects skips the
+// ones it is denied
+type PartialLister interface {
+	// ListClusterPartial list the clusters as ListCluster, with the regions or projects it skipped
+	ListClusterPartial() (clusters []ClusterInfo, skipped []string, err error)
+}
+
+// ListClusters list the clusters of the client, with the regions or projects it skipped
+func ListClusters(client Cluster) ([]ClusterInfo, []string, error) {
+	if lister, ok := client.(PartialLister); ok {
+		return lister.ListClusterPartial()
+	}
+	clusters, err := client.ListCluster()
+	return clusters, nil, err
+}
+
 // ClusterInfo ack cluster info
 type ClusterInfo struct {
-	Name       string
-	Account    string
-	ID         string
-	RegionID   string
-	K8sVersion string
-	ConsoleURL string
+	Name       string `json:"name"`
+	Account    string `json:"account,omitempty"`
+	ID         string `json:"id"`
+	RegionID   string `json:"regionID"`
+	K8sVersion string `json:"k8sVersion"`
+	ConsoleURL string `json:"consoleURL"`
 }
@@ -0,0 +1,154 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const digitalOceanURL = "https://api.digitalocean.com"
+
+var digitalOceanProvider = &Provider{
+	Name:     "DigitalOcean",
+	Alias:    []string{"digitalocean", "do", "doks"},
+	HomePage: "https://cloud.digitalocean.com/kubernetes/clusters",
+	Service:  "DOKS",
+	Credentials: []Credential{
+		{Env: "DIGITALOCEAN_TOKEN", Prompt: "DigitalOcean API token"},
+	},
+	ContextPrefix: "do",
+	New: func(s *Session) (Cluster, error) {
+		return &DigitalOcean{
+			Token:    s.Credentials["DIGITALOCEAN_TOKEN"],
+			RegionID: s.RegionID,
+		}, nil
+	},
+}
+
+// DigitalOcean struct of digitalocean cloud
+type DigitalOcean struct {
+	Token string
+	// RegionID limits the clusters to one region, all the regions are listed when empty
+	RegionID string
+
+	baseURL string
+}
+
+// doRequest request the digitalocean api with the token and return the body of a successful response
+func (d *DigitalOcean) doRequest(path string) ([]byte, error) {
+	base := d.baseURL
+	if base == "" {
+		base = digitalOceanURL
+	}
+	req, err := http.NewRequest(http.MethodGet, base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.Token)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("digitalocean api error %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return nil, fmt.Errorf("digitalocean api error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// GetRegionID get the regions doks is available in
+func (d *DigitalOcean) GetRegionID() ([]string, error) {
+	body, err := d.doRequest("/v2/kubernetes/options")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Options struct {
+			Regions []struct {
+				Slug string `json:"slug"`
+			} `json:"regions"`
+		} `json:"options"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	var regionList []string
+	for _, region := range result.Options.Regions {
+		regionList = append(regionList, region.Slug)
+	}
+	return regionList, nil
+}
+
+// ListCluster list doks cluster info
+func (d *DigitalOcean) ListCluster() (clusters []ClusterInfo, err error) {
+	next := "/v2/kubernetes/clusters?per_page=200"
+	for next != "" {
+		body, err := d.doRequest(next)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Clusters []struct {
+				ID      string `json:"id"`
+				Name    string `json:"name"`
+				Region  string `json:"region"`
+				Version string `json:"version"`
+			} `json:"kubernetes_clusters"`
+			Links struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		for _, cluster := range page.Clusters {
+			if d.RegionID != "" && cluster.Region != d.RegionID {
+				continue
+			}
+			clusters = append(clusters, ClusterInfo{
+				Name:       cluster.Name,
+				ID:         cluster.ID,
+				RegionID:   cluster.Region,
+				K8sVersion: cluster.Version,
+				ConsoleURL: fmt.Sprintf("https://cloud.digitalocean.com/kubernetes/clusters/%s", cluster.ID),
+			})
+		}
+		next = ""
+		if page.Links.Pages.Next != "" {
+			// only the path of the link is followed, the token is never sent to another host
+			link, err := url.Parse(page.Links.Pages.Next)
+			if err != nil {
+				return nil, err
+			}
+			next = link.RequestURI()
+		}
+	}
+	return clusters, nil
+}
+
+// GetKubeConfig get doks kubeConfig file
+func (d *DigitalOcean) GetKubeConfig(clusterID string) (string, error) {
+	body, err := d.doRequest(fmt.Sprintf("/v2/kubernetes/clusters/%s/kubeconfig", url.PathEscape(clusterID)))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const doKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://7e1a.k8s.ondigitalocean.com
+  name: do-ams3-web
+contexts:
+- context:
+    cluster: do-ams3-web
+    user: do-ams3-web-admin
+  name: do-ams3-web
+current-context: do-ams3-web
+users:
+- name: do-ams3-web-admin
+  user:
+    token: fake
+`
+
+// newFakeDigitalOcean start a fake digitalocean api serving two pages of clusters
+func newFakeDigitalOcean(t *testing.T) *DigitalOcean {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/kubernetes/clusters", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			// the api returns absolute links
+			fmt.Fprint(w, `{"kubernetes_clusters":[{"id":"c-1","name":"web","region":"ams3","version":"1.31.1-do.3"}],
+"links":{"pages":{"next":"https://api.digitalocean.com/v2/kubernetes/clusters?page=2&per_page=200"}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"kubernetes_clusters":[{"id":"c-2","name":"jobs","region":"nyc1","version":"1.30.5-do.5"}],"links":{}}`)
+	})
+	mux.HandleFunc("/v2/kubernetes/clusters/c-1/kubeconfig", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		fmt.Fprint(w, doKubeconfig)
+	})
+	mux.HandleFunc("/v2/kubernetes/options", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"options":{"regions":[{"name":"Amsterdam 3","slug":"ams3"},{"name":"New York 1","slug":"nyc1"}]}}`)
+	})
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer do-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"id":"unauthorized","message":"Unable to authenticate you"}`)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return &DigitalOcean{Token: "do-token", baseURL: server.URL}
+}
+
+func TestDigitalOcean_ListCluster(t *testing.T) {
+	do := newFakeDigitalOcean(t)
+	clusters, err := do.ListCluster()
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	want := []ClusterInfo{
+		{Name: "web", ID: "c-1", RegionID: "ams3", K8sVersion: "1.31.1-do.3", ConsoleURL: "https://cloud.digitalocean.com/kubernetes/clusters/c-1"},
+		{Name: "jobs", ID: "c-2", RegionID: "nyc1", K8sVersion: "1.30.5-do.5", ConsoleURL: "https://cloud.digitalocean.com/kubernetes/clusters/c-2"},
+	}
+	if !reflect.DeepEqual(clusters, want) {
+		t.Errorf("ListCluster() got = %+v, want %+v", clusters, want)
+	}
+
+	do.RegionID = "nyc1"
+	if clusters, err = do.ListCluster(); err != nil || len(clusters) != 1 || clusters[0].ID != "c-2" {
+		t.Errorf("ListCluster() of region got = %+v, error = %v", clusters, err)
+	}
+
+	do.Token = "wrong"
+	if _, err = do.ListCluster(); err == nil || err.Error() != "digitalocean api error 401: Unable to authenticate you" {
+		t.Errorf("ListCluster() error = %v", err)
+	}
+}
+
+func TestDigitalOcean_GetKubeConfig(t *testing.T) {
+	do := newFakeDigitalOcean(t)
+	kubeconfig, err := do.GetKubeConfig("c-1")
+	if err != nil || kubeconfig != doKubeconfig {
+		t.Errorf("GetKubeConfig() got = %q, error = %v", kubeconfig, err)
+	}
+	if _, err = do.GetKubeConfig("missing"); err == nil {
+		t.Errorf("GetKubeConfig() should fail for a missing cluster")
+	}
+}
+
+func TestDigitalOcean_GetRegionID(t *testing.T) {
+	do := newFakeDigitalOcean(t)
+	regions, err := do.GetRegionID()
+	if err != nil || !reflect.DeepEqual(regions, []string{"ams3", "nyc1"}) {
+		t.Errorf("GetRegionID() got = %v, error = %v", regions, err)
+	}
+}
@@ -0,0 +1,411 @@
+package cloud
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	gcpScope          = "https://www.googleapis.com/auth/cloud-platform"
+	gcpTokenURL       = "https://oauth2.googleapis.com/token"
+	gcpContainerURL   = "https://container.googleapis.com/v1"
+	gcpProjectsURL    = "https://cloudresourcemanager.googleapis.com/v1"
+	gcpAuthPlugin     = "gke-gcloud-auth-plugin"
+	gcpCredentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+	// gcpListWorkers is the number of projects whose clusters are listed at once
+	gcpListWorkers = 8
+)
+
+var gcpProvider = &Provider{
+	Name:     "GCP",
+	Alias:    []string{"gcp", "google", "gke"},
+	HomePage: "https://console.cloud.google.com/kubernetes",
+	Service:  "GKE",
+	// the credentials file is found by the GCP client like the Google SDKs do when the env is unset
+	Credentials: []Credential{
+		{Env: "GOOGLE_CLOUD_PROJECT", Optional: true},
+		{Env: gcpCredentialsEnv, Optional: true},
+	},
+	// keep the gke_PROJECT_LOCATION_NAME context of the kubeconfig
+	ContextName: func(cluster ClusterInfo) string {
+		return ""
+	},
+	Note: "please install the gke-gcloud-auth-plugin before normal use.",
+	New: func(s *Session) (Cluster, error) {
+		return &GCP{
+			CredentialsFile: s.Credentials[gcpCredentialsEnv],
+			ProjectID:       s.Credentials["GOOGLE_CLOUD_PROJECT"],
+			Location:        s.RegionID,
+		}, nil
+	},
+}
+
+// GCP struct of google cloud
+type GCP struct {
+	// CredentialsFile is a service account key or an ADC file, found like the Google SDKs do when empty
+	CredentialsFile string
+	// ProjectID limits the clusters to one project, all the active projects are listed when empty
+	ProjectID string
+	// Location limits the clusters to one region or zone, all the locations are listed when empty
+	Location string
+
+	// mu guards the client created on first use
+	mu           sync.Mutex
+	client       *http.Client
+	containerURL string
+	projectsURL  string
+}
+
+// gcpCredentials the service account key or authorized user file
+type gcpCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type gkeCluster struct {
+	Name                 string `json:"name"`
+	Location             string `json:"location"`
+	Endpoint             string `json:"endpoint"`
+	CurrentMasterVersion string `json:"currentMasterVersion"`
+	MasterAuth           struct {
+		ClusterCaCertificate string `json:"clusterCaCertificate"`
+	} `json:"masterAuth"`
+}
+
+// credentialsFile return the credentials file, from the flag, the env or the well-known gcloud ADC path
+func (g *GCP) credentialsFile() (string, error) {
+	if g.CredentialsFile != "" {
+		return g.CredentialsFile, nil
+	}
+	if path := os.Getenv(gcpCredentialsEnv); path != "" {
+		return path, nil
+	}
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		if runtime.GOOS == "windows" {
+			dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, ".config", "gcloud")
+		}
+	}
+	path := filepath.Join(dir, "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no google credentials found, set %s or run `gcloud auth application-default login`", gcpCredentialsEnv)
+	}
+	return path, nil
+}
+
+// getGCPClient get the http client authorized by the credentials file
+func (g *GCP) getGCPClient() (*http.Client, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.client != nil {
+		return g.client, nil
+	}
+	path, err := g.credentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cred gcpCredentials
+	if err = json.Unmarshal(content, &cred); err != nil {
+		return nil, fmt.Errorf("invalid google credentials file %s: %v", path, err)
+	}
+	tokenURL := cred.TokenURI
+	if tokenURL == "" {
+		tokenURL = gcpTokenURL
+	}
+	ctx := context.Background()
+	switch cred.Type {
+	case "service_account":
+		conf := &jwt.Config{
+			Email:        cred.ClientEmail,
+			PrivateKey:   []byte(cred.PrivateKey),
+			PrivateKeyID: cred.PrivateKeyID,
+			Scopes:       []string{gcpScope},
+			TokenURL:     tokenURL,
+		}
+		g.client = conf.Client(ctx)
+	case "authorized_user":
+		conf := &oauth2.Config{
+			ClientID:     cred.ClientID,
+			ClientSecret: cred.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL},
+			Scopes:       []string{gcpScope},
+		}
+		g.client = conf.Client(ctx, &oauth2.Token{RefreshToken: cred.RefreshToken})
+	default:
+		return nil, fmt.Errorf("unsupported google credentials type %q in %s", cred.Type, path)
+	}
+	return g.client, nil
+}
+
+// get request the google api and decode the json response into out
+func (g *GCP) get(rawURL string, out interface{}) error {
+	client, err := g.getGCPClient()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return &GCPError{StatusCode: resp.StatusCode, Message: apiErr.Error.Message}
+		}
+		return &GCPError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+	return json.Unmarshal(body, out)
+}
+
+// GCPError error returned by a google api
+type GCPError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *GCPError) Error() string {
+	return fmt.Sprintf("google api error %d: %s", e.StatusCode, e.Message)
+}
+
+func (g *GCP) endpoint(base, fallback string) string {
+	if base != "" {
+		return base
+	}
+	return fallback
+}
+
+// ListProjects list the active projects the credentials can see
+func (g *GCP) ListProjects() ([]string, error) {
+	var projects []string
+	pageToken := ""
+	for {
+		query := url.Values{"filter": {"lifecycleState:ACTIVE"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var page struct {
+			Projects []struct {
+				ProjectID string `json:"projectId"`
+			} `json:"projects"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		err := g.get(g.endpoint(g.projectsURL, gcpProjectsURL)+"/projects?"+query.Encode(), &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range page.Projects {
+			projects = append(projects, project.ProjectID)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// GetRegionID get region id of gke cluster
+func (g *GCP) GetRegionID() ([]string, error) {
+	// gke lists the clusters of all the locations at once, return nil
+	return nil, nil
+}
+
+// listProjectClusters list the gke clusters of the project
+func (g *GCP) listProjectClusters(project string) ([]gkeCluster, error) {
+	location := g.Location
+	if location == "" {
+		location = "-"
+	}
+	var result struct {
+		Clusters []gkeCluster `json:"clusters"`
+	}
+	err := g.get(fmt.Sprintf("%s/projects/%s/locations/%s/clusters",
+		g.endpoint(g.containerURL, gcpContainerURL), url.PathEscape(project), url.PathEscape(location)), &result)
+	return result.Clusters, err
+}
+
+// ListCluster list gke cluster info of the project, or of every active project concurrently
+func (g *GCP) ListCluster() ([]ClusterInfo, error) {
+	clusters, _, err := g.ListClusterPartial()
+	return clusters, err
+}
+
+// ListClusterPartial list gke cluster info as ListCluster, with the projects denied or without the gke api
+func (g *GCP) ListClusterPartial() (clusters []ClusterInfo, skipped []string, err error) {
+	if _, err = g.getGCPClient(); err != nil {
+		return nil, nil, err
+	}
+	projects := []string{g.ProjectID}
+	if g.ProjectID == "" {
+		if projects, err = g.ListProjects(); err != nil {
+			return nil, nil, err
+		}
+	}
+	results := make([][]gkeCluster, len(projects))
+	errs := make([]error, len(projects))
+	workers := make(chan struct{}, gcpListWorkers)
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func(i int, project string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			results[i], errs[i] = g.listProjectClusters(project)
+		}(i, project)
+	}
+	wg.Wait()
+	for i, project := range projects {
+		if errs[i] != nil {
+			// the projects without the gke api enabled, or not visible, are skipped when listing them all
+			if apiErr, ok := errs[i].(*GCPError); ok && g.ProjectID == "" &&
+				(apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusNotFound) {
+				skipped = append(skipped, project)
+				continue
+			}
+			return nil, nil, errs[i]
+		}
+		for _, cluster := range results[i] {
+			clusters = append(clusters, ClusterInfo{
+				Name:       cluster.Name,
+				Account:    project,
+				ID:         fmt.Sprintf("%s/%s/%s", project, cluster.Location, cluster.Name),
+				RegionID:   cluster.Location,
+				K8sVersion: cluster.CurrentMasterVersion,
+				ConsoleURL: fmt.Sprintf("https://console.cloud.google.com/kubernetes/clusters/details/%s/%s/details?project=%s",
+					cluster.Location, cluster.Name, project),
+			})
+		}
+	}
+	return clusters, skipped, nil
+}
+
+// parseGKEClusterID split the cluster id of ListCluster into project, location and name
+func parseGKEClusterID(clusterID string) (project, location, name string, err error) {
+	parts := strings.Split(strings.TrimPrefix(clusterID, "projects/"), "/")
+	switch {
+	case len(parts) == 3:
+		project, location, name = parts[0], parts[1], parts[2]
+	case len(parts) == 5 && parts[1] == "locations" && parts[3] == "clusters":
+		project, location, name = parts[0], parts[2], parts[4]
+	}
+	if project == "" || location == "" || name == "" {
+		return "", "", "", fmt.Errorf("invalid gke cluster id %s, expected PROJECT/LOCATION/NAME", clusterID)
+	}
+	return project, location, name, nil
+}
+
+// GetKubeConfigObj get gke kubeConfig using the gke-gcloud-auth-plugin, clusterID is PROJECT/LOCATION/NAME
+func (g *GCP) GetKubeConfigObj(clusterID string) (*clientcmdapi.Config, error) {
+	project, location, name, err := parseGKEClusterID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	var cluster gkeCluster
+	err = g.get(fmt.Sprintf("%s/projects/%s/locations/%s/clusters/%s", g.endpoint(g.containerURL, gcpContainerURL),
+		url.PathEscape(project), url.PathEscape(location), url.PathEscape(name)), &cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	decodePem, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion:         "client.authentication.k8s.io/v1beta1",
+		Command:            gcpAuthPlugin,
+		InstallHint:        "Install gke-gcloud-auth-plugin for use with kubectl by following https://cloud.google.com/kubernetes-engine/docs/how-to/cluster-access-for-kubectl#install_plugin",
+		ProvideClusterInfo: true,
+		InteractiveMode:    clientcmdapi.IfAvailableExecInteractiveMode,
+	}
+	// the plugin authenticates with the credentials the clusters were listed with, not the account
+	// active in gcloud
+	path, err := g.credentialsFile()
+	if err != nil {
+		return nil, err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	exec.Args = []string{"--use_application_default_credentials"}
+	exec.Env = []clientcmdapi.ExecEnvVar{{Name: gcpCredentialsEnv, Value: path}}
+
+	// the context name gcloud container clusters get-credentials uses
+	contextName := fmt.Sprintf("gke_%s_%s_%s", project, location, name)
+	kubeconfig := &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			contextName: {
+				Server:                   "https://" + cluster.Endpoint,
+				CertificateAuthorityData: decodePem,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			contextName: {
+				Exec: exec,
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			contextName: {
+				Cluster:  contextName,
+				AuthInfo: contextName,
+			},
+		},
+		CurrentContext: contextName,
+	}
+
+	return kubeconfig, nil
+}
+
+// GetKubeConfig get gke kubeConfig file
+func (g *GCP) GetKubeConfig(clusterID string) (string, error) {
+	config, err := g.GetKubeConfigObj(clusterID)
+	if err != nil {
+		return "", err
+	}
+	content, err := clientcmd.Write(*config)
+	return string(content), err
+}
@@ -0,0 +1,224 @@
+package cloud
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// newFakeGCP start a fake token, resource manager and container api, and return a GCP using it
+// with a service account key file
+func newFakeGCP(t *testing.T) *GCP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer fake-token" {
+				http.Error(w, `{"error":{"message":"unauthenticated"}}`, http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.FormValue("assertion") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"fake-token","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc("/v1/projects", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"projects":[{"projectId":"prod"}],"nextPageToken":"next"}`)
+			return
+		}
+		fmt.Fprint(w, `{"projects":[{"projectId":"dev"},{"projectId":"no-gke"}]}`)
+	}))
+	ca := base64.StdEncoding.EncodeToString([]byte("fake-ca"))
+	mux.HandleFunc("/container/projects/prod/locations/-/clusters", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"clusters":[{"name":"web","location":"europe-west1","currentMasterVersion":"1.30.5-gke.1014001"}]}`)
+	}))
+	mux.HandleFunc("/container/projects/dev/locations/-/clusters", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"clusters":[{"name":"test","location":"us-central1-a","currentMasterVersion":"1.31.1-gke.1678000"}]}`)
+	}))
+	mux.HandleFunc("/container/projects/dev/locations/us-central1-a/clusters", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"clusters":[{"name":"test","location":"us-central1-a","currentMasterVersion":"1.31.1-gke.1678000"}]}`)
+	}))
+	mux.HandleFunc("/container/projects/no-gke/locations/-/clusters", authorized(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"Kubernetes Engine API has not been used in project no-gke"}}`, http.StatusForbidden)
+	}))
+	mux.HandleFunc("/container/projects/prod/locations/europe-west1/clusters/web", authorized(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name":"web","location":"europe-west1","endpoint":"203.0.113.10","masterAuth":{"clusterCaCertificate":%q}}`, ca)
+	}))
+
+	content, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "kubecm@prod.iam.gserviceaccount.com",
+		"private_key_id": "1",
+		"private_key":    string(keyPEM),
+		"token_uri":      server.URL + "/token",
+	})
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err = os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return &GCP{
+		CredentialsFile: path,
+		containerURL:    server.URL + "/container",
+		projectsURL:     server.URL + "/v1",
+	}
+}
+
+func TestGCP_ListCluster(t *testing.T) {
+	gcp := newFakeGCP(t)
+	clusters, err := gcp.ListCluster()
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	var ids []string
+	for _, cluster := range clusters {
+		ids = append(ids, cluster.ID)
+	}
+	// sorted by project, no-gke is skipped
+	want := []string{"dev/us-central1-a/test", "prod/europe-west1/web"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("ListCluster() got = %v, want %v", ids, want)
+	}
+	if clusters[1].Account != "prod" || clusters[1].RegionID != "europe-west1" || clusters[1].K8sVersion != "1.30.5-gke.1014001" {
+		t.Errorf("ListCluster() got = %+v", clusters[1])
+	}
+	if _, skipped, err := ListClusters(gcp); err != nil || !reflect.DeepEqual(skipped, []string{"no-gke"}) {
+		t.Errorf("ListClusters() skipped %v, error = %v, want no-gke", skipped, err)
+	}
+
+	gcp.ProjectID, gcp.Location = "dev", "us-central1-a"
+	if clusters, err = gcp.ListCluster(); err != nil || len(clusters) != 1 || clusters[0].Name != "test" {
+		t.Errorf("ListCluster() of project got = %+v, error = %v", clusters, err)
+	}
+	gcp.ProjectID, gcp.Location = "no-gke", ""
+	if _, err = gcp.ListCluster(); err == nil {
+		t.Errorf("ListCluster() of a project without gke should fail")
+	}
+}
+
+func TestGCP_GetKubeConfigObj(t *testing.T) {
+	gcp := newFakeGCP(t)
+	config, err := gcp.GetKubeConfigObj("prod/europe-west1/web")
+	if err != nil {
+		t.Fatalf("GetKubeConfigObj() error = %v", err)
+	}
+	name := "gke_prod_europe-west1_web"
+	if config.CurrentContext != name || config.Contexts[name] == nil {
+		t.Fatalf("GetKubeConfigObj() got context %q", config.CurrentContext)
+	}
+	cluster := config.Clusters[name]
+	if cluster.Server != "https://203.0.113.10" || string(cluster.CertificateAuthorityData) != "fake-ca" {
+		t.Errorf("GetKubeConfigObj() got cluster %+v", cluster)
+	}
+	exec := config.AuthInfos[name].Exec
+	if exec.Command != "gke-gcloud-auth-plugin" || !exec.ProvideClusterInfo ||
+		len(exec.Env) != 1 || exec.Env[0].Name != "GOOGLE_APPLICATION_CREDENTIALS" || exec.Env[0].Value != gcp.CredentialsFile {
+		t.Errorf("GetKubeConfigObj() got exec %+v", exec)
+	}
+
+	if _, err = gcp.GetKubeConfigObj("prod/web"); err == nil {
+		t.Errorf("GetKubeConfigObj() should reject an invalid cluster id")
+	}
+	if _, err = gcp.GetKubeConfigObj("prod/europe-west1/missing"); err == nil {
+		t.Errorf("GetKubeConfigObj() should fail for a missing cluster")
+	}
+}
+
+func TestGCP_New(t *testing.T) {
+	fake := newFakeGCP(t)
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv(gcpCredentialsEnv, fake.CredentialsFile)
+	session := &Session{}
+	if err := session.Resolve(gcpProvider.Credentials...); err != nil {
+		t.Fatal(err)
+	}
+	client, err := gcpProvider.New(session)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	gcp := client.(*GCP)
+	if gcp.CredentialsFile != fake.CredentialsFile {
+		t.Errorf("New() got credentials file %q, want the one of %s", gcp.CredentialsFile, gcpCredentialsEnv)
+	}
+	gcp.containerURL, gcp.projectsURL = fake.containerURL, fake.projectsURL
+	config, err := GetKubeConfigObj(gcp, "prod/europe-west1/web")
+	if err != nil {
+		t.Fatalf("GetKubeConfigObj() error = %v", err)
+	}
+	// the plugin uses the service account the clusters were listed with
+	exec := config.AuthInfos[config.CurrentContext].Exec
+	want := []clientcmdapi.ExecEnvVar{{Name: gcpCredentialsEnv, Value: fake.CredentialsFile}}
+	if !reflect.DeepEqual(exec.Args, []string{"--use_application_default_credentials"}) || !reflect.DeepEqual(exec.Env, want) {
+		t.Errorf("GetKubeConfigObj() got exec args %v, env %v", exec.Args, exec.Env)
+	}
+}
+
+func Test_parseGKEClusterID(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    []string
+		wantErr bool
+	}{
+		{id: "p/us-central1/c", want: []string{"p", "us-central1", "c"}},
+		{id: "projects/p/locations/us-central1/clusters/c", want: []string{"p", "us-central1", "c"}},
+		{id: "p/c", wantErr: true},
+		{id: "p//c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			project, location, name, err := parseGKEClusterID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGKEClusterID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual([]string{project, location, name}, tt.want) {
+				t.Errorf("parseGKEClusterID() got = %v, want %v", []string{project, location, name}, tt.want)
+			}
+		})
+	}
+}
+
+func TestGCP_credentialsFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", dir)
+	gcp := &GCP{}
+	if _, err := gcp.credentialsFile(); err == nil {
+		t.Errorf("credentialsFile() should fail without any credentials")
+	}
+	adc := filepath.Join(dir, "application_default_credentials.json")
+	if err := os.WriteFile(adc, []byte(`{"type":"authorized_user"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := gcp.credentialsFile(); err != nil || got != adc {
+		t.Errorf("credentialsFile() got = %v, error = %v", got, err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "/tmp/key.json")
+	if got, _ := gcp.credentialsFile(); got != "/tmp/key.json" {
+		t.Errorf("credentialsFile() got = %v, want the env", got)
+	}
+}
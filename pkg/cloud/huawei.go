@@ -0,0 +1,220 @@
+package cloud
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const huaweiSignAlgorithm = "SDK-HMAC-SHA256"
+
+var huaweiCloudProvider = &Provider{
+	Name:     "HuaweiCloud",
+	Alias:    []string{"huaweicloud", "huawei", "cce"},
+	HomePage: "https://console.huaweicloud.com/cce2.0",
+	Service:  "CCE",
+	Credentials: []Credential{
+		{Env: "HUAWEICLOUD_SDK_AK", Prompt: "HuaweiCloud Access Key ID"},
+		{Env: "HUAWEICLOUD_SDK_SK", Prompt: "HuaweiCloud Secret Access Key"},
+	},
+	Regional:      true,
+	ContextPrefix: "huawei",
+	New: func(s *Session) (Cluster, error) {
+		return &HuaweiCloud{
+			AccessKeyID:     s.Credentials["HUAWEICLOUD_SDK_AK"],
+			AccessKeySecret: s.Credentials["HUAWEICLOUD_SDK_SK"],
+			RegionID:        s.RegionID,
+		}, nil
+	},
+}
+
+// HuaweiCloud struct of huawei cloud
+type HuaweiCloud struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	RegionID        string
+
+	// endpoint replaces the iam and cce endpoints, used by the tests
+	endpoint string
+}
+
+// url return the url of the api path of the iam or regional cce service
+func (h *HuaweiCloud) url(service, path string) string {
+	if h.endpoint != "" {
+		return h.endpoint + path
+	}
+	if service == "iam" {
+		return "https://iam.myhuaweicloud.com" + path
+	}
+	return fmt.Sprintf("https://%s.%s.myhuaweicloud.com%s", service, h.RegionID, path)
+}
+
+// request call the huawei cloud api signed with the AK/SK and decode the json response into out
+func (h *HuaweiCloud) request(method, rawURL string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	huaweiSign(req, body, h.AccessKeyID, h.AccessKeySecret, time.Now())
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		var apiErr struct {
+			ErrorCode string `json:"error_code"`
+			ErrorMsg  string `json:"error_msg"`
+			Code      string `json:"code"`
+			Message   string `json:"message"`
+		}
+		// iam and cce name the fields of the error differently
+		if json.Unmarshal(content, &apiErr) == nil && apiErr.ErrorMsg+apiErr.Message != "" {
+			return fmt.Errorf("huawei cloud api error %d: %s %s", resp.StatusCode,
+				apiErr.ErrorCode+apiErr.Code, apiErr.ErrorMsg+apiErr.Message)
+		}
+		return fmt.Errorf("huawei cloud api error %d: %s", resp.StatusCode, strings.TrimSpace(string(content)))
+	}
+	return json.Unmarshal(content, out)
+}
+
+// huaweiSign sign the request with the APIG AK/SK algorithm of huawei cloud
+func huaweiSign(req *http.Request, body []byte, ak, sk string, now time.Time) {
+	date := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Sdk-Date", date)
+	signedHeaders, canonicalHeaders := canonicalHeaders(req, []string{"content-type", "host", "x-sdk-date"})
+	canonicalURI := canonicalPath(req.URL)
+	if !strings.HasSuffix(canonicalURI, "/") {
+		canonicalURI += "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+	stringToSign := strings.Join([]string{huaweiSignAlgorithm, date, hashHex([]byte(canonicalRequest))}, "\n")
+	signature := hex.EncodeToString(hmacSHA256([]byte(sk), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Access=%s, SignedHeaders=%s, Signature=%s",
+		huaweiSignAlgorithm, ak, signedHeaders, signature))
+}
+
+// huaweiProject iam project, named after its region
+type huaweiProject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// listProjects list the iam projects of the account, only the one of the region when name is set
+func (h *HuaweiCloud) listProjects(name string) ([]huaweiProject, error) {
+	path := "/v3/projects"
+	if name != "" {
+		path += "?name=" + url.QueryEscape(name)
+	}
+	var result struct {
+		Projects []huaweiProject `json:"projects"`
+	}
+	err := h.request(http.MethodGet, h.url("iam", path), nil, &result)
+	return result.Projects, err
+}
+
+// projectID return the id of the iam project of the region
+func (h *HuaweiCloud) projectID() (string, error) {
+	projects, err := h.listProjects(h.RegionID)
+	if err != nil {
+		return "", err
+	}
+	if len(projects) == 0 {
+		return "", fmt.Errorf("no huawei cloud project found for region %s", h.RegionID)
+	}
+	return projects[0].ID, nil
+}
+
+// GetRegionID get region id of cce cluster
+func (h *HuaweiCloud) GetRegionID() ([]string, error) {
+	projects, err := h.listProjects("")
+	if err != nil {
+		return nil, err
+	}
+	var regionList []string
+	for _, project := range projects {
+		// MOS is the project of the global services, sub projects are named REGION_NAME
+		if project.Name == "MOS" || strings.Contains(project.Name, "_") {
+			continue
+		}
+		regionList = append(regionList, project.Name)
+	}
+	sort.Strings(regionList)
+	return regionList, nil
+}
+
+// ListCluster list cce cluster info
+func (h *HuaweiCloud) ListCluster() (clusters []ClusterInfo, err error) {
+	projectID, err := h.projectID()
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+				UID  string `json:"uid"`
+			} `json:"metadata"`
+			Spec struct {
+				Version string `json:"version"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	err = h.request(http.MethodGet, h.url("cce", fmt.Sprintf("/api/v3/projects/%s/clusters", projectID)), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range result.Items {
+		clusters = append(clusters, ClusterInfo{
+			Name:       cluster.Metadata.Name,
+			ID:         cluster.Metadata.UID,
+			RegionID:   h.RegionID,
+			K8sVersion: cluster.Spec.Version,
+			ConsoleURL: fmt.Sprintf("https://console.huaweicloud.com/cce2.0/?region=%s#/cce/cluster/%s/info", h.RegionID, cluster.Metadata.UID),
+		})
+	}
+	return clusters, nil
+}
+
+// GetKubeConfig get cce kubeConfig file, valid for 30 days
+func (h *HuaweiCloud) GetKubeConfig(clusterID string) (string, error) {
+	projectID, err := h.projectID()
+	if err != nil {
+		return "", err
+	}
+	var kubeconfig json.RawMessage
+	err = h.request(http.MethodPost, h.url("cce", fmt.Sprintf("/api/v3/projects/%s/clusters/%s/clustercert",
+		projectID, url.PathEscape(clusterID))), map[string]int{"duration": 30}, &kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	// the kubeconfig is returned as json, which is valid yaml
+	return string(kubeconfig), nil
+}
@@ -0,0 +1,119 @@
+package cloud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifySignature sign the request as received by the server again with the expected secret,
+// and compare it to the signature of the client
+func verifySignature(t *testing.T, r *http.Request, sign func(req *http.Request, body []byte, now time.Time), dateHeader string) bool {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	now, err := time.Parse("20060102T150405Z", r.Header.Get(dateHeader))
+	if err != nil {
+		t.Errorf("invalid %s header %q", dateHeader, r.Header.Get(dateHeader))
+		return false
+	}
+	req := r.Clone(r.Context())
+	req.URL.Host = r.Host
+	sign(req, body, now)
+	return r.Header.Get("Authorization") == req.Header.Get("Authorization")
+}
+
+func newFakeHuaweiCloud(t *testing.T) *HuaweiCloud {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifySignature(t, r, func(req *http.Request, body []byte, now time.Time) {
+			huaweiSign(req, body, "hw-ak", "hw-sk", now)
+		}, "X-Sdk-Date") {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error_code":"APIGW.0301","error_msg":"Incorrect IAM authentication information"}`)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v3/projects" && r.URL.Query().Get("name") == "cn-north-4":
+			fmt.Fprint(w, `{"projects":[{"id":"p-north","name":"cn-north-4"}]}`)
+		case r.URL.Path == "/v3/projects" && r.URL.Query().Get("name") == "":
+			fmt.Fprint(w, `{"projects":[{"id":"p-mos","name":"MOS"},{"id":"p-north","name":"cn-north-4"},
+{"id":"p-sub","name":"cn-north-4_dev"},{"id":"p-east","name":"cn-east-3"}]}`)
+		case r.URL.Path == "/v3/projects":
+			fmt.Fprint(w, `{"projects":[]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/projects/p-north/clusters":
+			fmt.Fprint(w, `{"kind":"Cluster","items":[{"metadata":{"name":"web","uid":"c-1"},"spec":{"version":"v1.29"}}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/projects/p-north/clusters/c-1/clustercert":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"duration":30}` {
+				t.Errorf("clustercert body = %s", body)
+			}
+			fmt.Fprint(w, `{"kind":"Config","apiVersion":"v1","clusters":[{"name":"externalCluster","cluster":{"server":"https://1.2.3.4:5443"}}],
+"users":[{"name":"user","user":{"token":"fake"}}],"contexts":[{"name":"external","context":{"cluster":"externalCluster","user":"user"}}],
+"current-context":"external"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"CCE.01404001","message":"The resource does not exist"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &HuaweiCloud{AccessKeyID: "hw-ak", AccessKeySecret: "hw-sk", RegionID: "cn-north-4", endpoint: server.URL}
+}
+
+func TestHuaweiCloud_GetRegionID(t *testing.T) {
+	hw := newFakeHuaweiCloud(t)
+	regions, err := hw.GetRegionID()
+	if err != nil || !reflect.DeepEqual(regions, []string{"cn-east-3", "cn-north-4"}) {
+		t.Errorf("GetRegionID() got = %v, error = %v", regions, err)
+	}
+}
+
+func TestHuaweiCloud_ListCluster(t *testing.T) {
+	hw := newFakeHuaweiCloud(t)
+	clusters, err := hw.ListCluster()
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	if len(clusters) != 1 || clusters[0].ID != "c-1" || clusters[0].Name != "web" ||
+		clusters[0].RegionID != "cn-north-4" || clusters[0].K8sVersion != "v1.29" {
+		t.Errorf("ListCluster() got = %+v", clusters)
+	}
+
+	hw.RegionID = "ap-southeast-1"
+	if _, err = hw.ListCluster(); err == nil || !strings.Contains(err.Error(), "no huawei cloud project found") {
+		t.Errorf("ListCluster() of a region without project error = %v", err)
+	}
+
+	hw.RegionID, hw.AccessKeySecret = "cn-north-4", "wrong"
+	if _, err = hw.ListCluster(); err == nil || !strings.Contains(err.Error(), "APIGW.0301") {
+		t.Errorf("ListCluster() with a wrong secret error = %v", err)
+	}
+}
+
+func TestHuaweiCloud_GetKubeConfig(t *testing.T) {
+	hw := newFakeHuaweiCloud(t)
+	kubeconfig, err := hw.GetKubeConfig("c-1")
+	if err != nil || !strings.Contains(kubeconfig, `"current-context":"external"`) {
+		t.Errorf("GetKubeConfig() got = %s, error = %v", kubeconfig, err)
+	}
+	if _, err = hw.GetKubeConfig("missing"); err == nil || !strings.Contains(err.Error(), "CCE.01404001") {
+		t.Errorf("GetKubeConfig() of a missing cluster error = %v", err)
+	}
+}
+
+func Test_huaweiSign(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.myhuaweicloud.com/v3/projects?name=cn-north-4", nil)
+	req.Header.Set("Content-Type", "application/json")
+	huaweiSign(req, nil, "AK", "SK", time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	if req.Header.Get("X-Sdk-Date") != "20240501T080000Z" {
+		t.Errorf("X-Sdk-Date = %s", req.Header.Get("X-Sdk-Date"))
+	}
+	prefix := "SDK-HMAC-SHA256 Access=AK, SignedHeaders=content-type;host;x-sdk-date, Signature="
+	if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, prefix) || len(auth) != len(prefix)+64 {
+		t.Errorf("Authorization = %s", auth)
+	}
+}
@@ -0,0 +1,191 @@
+package cloud
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Prompter asks the user for the input a provider needs, it is nil in non-interactive mode
+type Prompter interface {
+	Input(label string) (string, error)
+	Select(label string, options []string) (int, error)
+}
+
+// Credential an environment variable a provider reads a credential from
+type Credential struct {
+	Env string
+	// Prompt is the label asking for the credential when the variable is unset
+	Prompt string
+	// Optional credentials are left empty instead of asked for
+	Optional bool
+}
+
+// Session the credentials, region and prompter a provider client is created with
+type Session struct {
+	// Credentials are the resolved values, by env var
+	Credentials map[string]string
+	// RegionID is the region of regional providers, a filter of the clusters for the others
+	RegionID string
+	Prompter Prompter
+	// Profile, RoleARN and ExternalID are used by the providers authenticating with a profile of
+	// a shared config and assuming a role, AWS
+	Profile    string
+	RoleARN    string
+	ExternalID string
+}
+
+// MissingInputError input that is asked for interactively, but has to be given by a flag or env var
+// in non-interactive mode
+type MissingInputError struct {
+	// Flags are the alternative flags giving the input
+	Flags []string
+	// Env are the env vars that all have to be set
+	Env []string
+}
+
+func (e *MissingInputError) Error() string {
+	if len(e.Env) > 0 {
+		return fmt.Sprintf("%s must be set in non-interactive mode", strings.Join(e.Env, ", "))
+	}
+	flags := make([]string, len(e.Flags))
+	for i, flag := range e.Flags {
+		flags[i] = "--" + flag
+	}
+	return fmt.Sprintf("%s is required in non-interactive mode", strings.Join(flags, " or "))
+}
+
+// Resolve read the credentials from the environment. When a required one is unset they are all
+// asked for, since a key id from the environment rarely goes with a typed secret.
+// In non-interactive mode a *MissingInputError names the unset ones.
+func (s *Session) Resolve(creds ...Credential) error {
+	if s.Credentials == nil {
+		s.Credentials = make(map[string]string)
+	}
+	var missing []string
+	for _, cred := range creds {
+		value, ok := os.LookupEnv(cred.Env)
+		s.Credentials[cred.Env] = value
+		if !ok && !cred.Optional {
+			missing = append(missing, cred.Env)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if s.Prompter == nil {
+		return &MissingInputError{Env: missing}
+	}
+	for _, cred := range creds {
+		if cred.Optional {
+			continue
+		}
+		value, err := s.Prompter.Input(cred.Prompt)
+		if err != nil {
+			return err
+		}
+		s.Credentials[cred.Env] = value
+	}
+	return nil
+}
+
+// Provider a cloud provider of kubernetes clusters
+type Provider struct {
+	Name     string
+	Alias    []string
+	HomePage string
+	Service  string
+	// Credentials are resolved before the client is created
+	Credentials []Credential
+	// Regional providers list the clusters of one region, selected when --region_id is not set
+	Regional bool
+	// RegionPrompt is the label of the region selection, "Select Region ID" when empty
+	RegionPrompt string
+	// ContextName return the name the kubeconfig of the cluster is added under, cluster.Name may be empty
+	// when added by --cluster_id. An empty name keeps the current context of the kubeconfig.
+	// The cluster name, or PREFIX-ID, is used when nil.
+	ContextName func(cluster ClusterInfo) string
+	// ContextPrefix prefixes the cluster id when adding by --cluster_id
+	ContextPrefix string
+	// ParseClusterID return the cluster of an id of --cluster_id or of a recorded context, with the id in
+	// the form ListCluster returns it. regionID is the region of an id without one, a *MissingInputError
+	// of region_id is returned when it is needed and empty. The id is kept when nil.
+	ParseClusterID func(clusterID, regionID string) (ClusterInfo, error)
+	// Note is printed after the kubeconfig is added
+	Note string
+	// New create the client of the provider
+	New func(s *Session) (Cluster, error)
+}
+
+// Providers the supported cloud providers, in the order they are offered
+var Providers = []*Provider{
+	aliCloudProvider,
+	tencentCloudProvider,
+	rancherProvider,
+	awsProvider,
+	azureProvider,
+	gcpProvider,
+	digitalOceanProvider,
+	huaweiCloudProvider,
+	volcengineProvider,
+}
+
+// LookupProvider return the provider of the alias, nil when there is none
+func LookupProvider(alias string) *Provider {
+	for _, provider := range Providers {
+		for _, a := range provider.Alias {
+			if a == alias {
+				return provider
+			}
+		}
+	}
+	return nil
+}
+
+// Aliases return the aliases of all the providers
+func Aliases() []string {
+	var aliases []string
+	for _, provider := range Providers {
+		aliases = append(aliases, provider.Alias...)
+	}
+	return aliases
+}
+
+// ClusterContextName return the name the kubeconfig of the cluster is added under
+func (p *Provider) ClusterContextName(cluster ClusterInfo) string {
+	if p.ContextName != nil {
+		return p.ContextName(cluster)
+	}
+	if cluster.Name != "" {
+		return cluster.Name
+	}
+	return fmt.Sprintf("%s-%s", p.ContextPrefix, cluster.ID)
+}
+
+// ClusterByID return the cluster of an id of --cluster_id or of a recorded context in the region
+func (p *Provider) ClusterByID(clusterID, regionID string) (ClusterInfo, error) {
+	if p.ParseClusterID != nil {
+		return p.ParseClusterID(clusterID, regionID)
+	}
+	return ClusterInfo{ID: clusterID, RegionID: regionID}, nil
+}
+
+// kubeConfigObjGetter is implemented by the providers building the kubeconfig themselves
+type kubeConfigObjGetter interface {
+	GetKubeConfigObj(clusterID string) (*clientcmdapi.Config, error)
+}
+
+// GetKubeConfigObj get the kubeconfig of the cluster from the client
+func GetKubeConfigObj(client Cluster, clusterID string) (*clientcmdapi.Config, error) {
+	if getter, ok := client.(kubeConfigObjGetter); ok {
+		return getter.GetKubeConfigObj(clusterID)
+	}
+	kubeconfig, err := client.GetKubeConfig(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Load([]byte(kubeconfig))
+}
@@ -0,0 +1,113 @@
+package cloud
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testPrompter answers the inputs by label
+type testPrompter struct {
+	inputs map[string]string
+	asked  []string
+}
+
+func (p *testPrompter) Input(label string) (string, error) {
+	p.asked = append(p.asked, label)
+	return p.inputs[label], nil
+}
+
+func (p *testPrompter) Select(label string, options []string) (int, error) {
+	p.asked = append(p.asked, label)
+	return 0, nil
+}
+
+func TestSession_Resolve(t *testing.T) {
+	creds := []Credential{
+		{Env: "TEST_KEY_ID", Prompt: "Key ID"},
+		{Env: "TEST_KEY_SECRET", Prompt: "Key Secret"},
+		{Env: "TEST_PROJECT", Optional: true},
+	}
+	inputs := map[string]string{"Key ID": "typed-id", "Key Secret": "typed-secret"}
+	tests := []struct {
+		name      string
+		env       map[string]string
+		want      map[string]string
+		wantAsked []string
+	}{
+		{
+			name:      "env",
+			env:       map[string]string{"TEST_KEY_ID": "env-id", "TEST_KEY_SECRET": "env-secret", "TEST_PROJECT": "p"},
+			want:      map[string]string{"TEST_KEY_ID": "env-id", "TEST_KEY_SECRET": "env-secret", "TEST_PROJECT": "p"},
+			wantAsked: nil,
+		},
+		{
+			name:      "optional unset",
+			env:       map[string]string{"TEST_KEY_ID": "env-id", "TEST_KEY_SECRET": "env-secret"},
+			want:      map[string]string{"TEST_KEY_ID": "env-id", "TEST_KEY_SECRET": "env-secret", "TEST_PROJECT": ""},
+			wantAsked: nil,
+		},
+		{
+			name:      "one unset",
+			env:       map[string]string{"TEST_KEY_ID": "env-id"},
+			want:      map[string]string{"TEST_KEY_ID": "typed-id", "TEST_KEY_SECRET": "typed-secret", "TEST_PROJECT": ""},
+			wantAsked: []string{"Key ID", "Key Secret"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, cred := range creds {
+				// restored after the test
+				t.Setenv(cred.Env, tt.env[cred.Env])
+				if _, ok := tt.env[cred.Env]; !ok {
+					os.Unsetenv(cred.Env)
+				}
+			}
+			prompter := &testPrompter{inputs: inputs}
+			s := &Session{Prompter: prompter}
+			if err := s.Resolve(creds...); err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if !reflect.DeepEqual(s.Credentials, tt.want) {
+				t.Errorf("Resolve() got = %v, want %v", s.Credentials, tt.want)
+			}
+			if !reflect.DeepEqual(prompter.asked, tt.wantAsked) {
+				t.Errorf("Resolve() asked %v, want %v", prompter.asked, tt.wantAsked)
+			}
+		})
+	}
+}
+
+func TestLookupProvider(t *testing.T) {
+	for _, provider := range Providers {
+		for _, alias := range provider.Alias {
+			if got := LookupProvider(alias); got != provider {
+				t.Errorf("LookupProvider(%s) = %v, want %v", alias, got.Name, provider.Name)
+			}
+		}
+	}
+	if LookupProvider("alibabaclou") != nil {
+		t.Errorf("LookupProvider() of an unknown alias should be nil")
+	}
+}
+
+func TestProvider_ClusterContextName(t *testing.T) {
+	tests := []struct {
+		alias   string
+		cluster ClusterInfo
+		want    string
+	}{
+		{alias: "ack", cluster: ClusterInfo{ID: "c-1", Name: "web"}, want: "web"},
+		{alias: "ack", cluster: ClusterInfo{ID: "c-1"}, want: "alicloud-c-1"},
+		{alias: "tke", cluster: ClusterInfo{ID: "cls-1"}, want: "tencent-cls-1"},
+		{alias: "eks", cluster: ClusterInfo{ID: "web", Name: "web"}, want: "aws-web"},
+		{alias: "gke", cluster: ClusterInfo{ID: "p/l/web", Name: "web"}, want: ""},
+		{alias: "aks", cluster: ClusterInfo{ID: "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/web", Name: "web"}, want: "web"},
+		{alias: "aks", cluster: ClusterInfo{ID: "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/web"}, want: "web"},
+	}
+	for _, tt := range tests {
+		if got := LookupProvider(tt.alias).ClusterContextName(tt.cluster); got != tt.want {
+			t.Errorf("ClusterContextName() of %s %+v = %q, want %q", tt.alias, tt.cluster, got, tt.want)
+		}
+	}
+}
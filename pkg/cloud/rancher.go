@@ -13,6 +13,24 @@ type Rancher struct {
 	APIKey    string
 }
 
+var rancherProvider = &Provider{
+	Name:     "Rancher",
+	Alias:    []string{"rancher"},
+	HomePage: "https://rancher.com",
+	Service:  "Rancher",
+	Credentials: []Credential{
+		{Env: "RANCHER_SERVER_URL", Prompt: "Rancher API serverURL"},
+		{Env: "RANCHER_API_KEY", Prompt: "Rancher API key"},
+	},
+	ContextPrefix: "rancher",
+	New: func(s *Session) (Cluster, error) {
+		return &Rancher{
+			ServerURL: s.Credentials["RANCHER_SERVER_URL"],
+			APIKey:    s.Credentials["RANCHER_API_KEY"],
+		}, nil
+	},
+}
+
 // getRancherClient get rancher client
 func getRancherClient(serverURL, apiKey string) (*managementClient.Client, error) {
 	if !strings.HasSuffix(serverURL, "/v3") {
@@ -0,0 +1,55 @@
+package cloud
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// canonicalHeaders return the signed header names and their canonical form, of the headers present in the request
+func canonicalHeaders(req *http.Request, names []string) (string, string) {
+	var signed []string
+	var lines []string
+	sort.Strings(names)
+	for _, name := range names {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		if value == "" {
+			continue
+		}
+		signed = append(signed, name)
+		lines = append(lines, name+":"+strings.TrimSpace(value)+"\n")
+	}
+	return strings.Join(signed, ";"), strings.Join(lines, "")
+}
+
+// canonicalPath return the uri encoded path of the url
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalQuery return the query of the url sorted by key, with spaces encoded as %20
+func canonicalQuery(u *url.URL) string {
+	return strings.ReplaceAll(u.Query().Encode(), "+", "%20")
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
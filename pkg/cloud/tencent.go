@@ -16,6 +16,27 @@ type TencentCloud struct {
 	RegionID  string
 }
 
+var tencentCloudProvider = &Provider{
+	Name:     "TencentCloud",
+	Alias:    []string{"tencentcloud", "tencent", "tke"},
+	HomePage: "https://console.cloud.tencent.com/tke",
+	Service:  "TKE",
+	Credentials: []Credential{
+		{Env: "TENCENTCLOUD_SECRET_ID", Prompt: "TencentCloud API secretId"},
+		{Env: "TENCENTCLOUD_SECRET_KEY", Prompt: "TencentCloud API secretKey"},
+	},
+	Regional:      true,
+	RegionPrompt:  "Select Region Name",
+	ContextPrefix: "tencent",
+	New: func(s *Session) (Cluster, error) {
+		return &TencentCloud{
+			SecretID:  s.Credentials["TENCENTCLOUD_SECRET_ID"],
+			SecretKey: s.Credentials["TENCENTCLOUD_SECRET_KEY"],
+			RegionID:  s.RegionID,
+		}, nil
+	},
+}
+
 // getTenClient get tencent openapi client
 func getTenClient(SecretID, SecretKey, RegionID string) (*tke.Client, error) {
 	credential := common.NewCredential(
@@ -0,0 +1,242 @@
+package cloud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	volcengineURL       = "https://open.volcengineapi.com"
+	volcengineService   = "vke"
+	volcengineVersion   = "2022-05-12"
+	volcengineAlgorithm = "HMAC-SHA256"
+	volcenginePageSize  = 100
+	// volcengineValidHoursEnv opts in to create a public kubeconfig valid for the hours when a cluster has none
+	volcengineValidHoursEnv = "VOLCENGINE_KUBECONFIG_VALID_HOURS"
+)
+
+var volcengineProvider = &Provider{
+	Name:     "Volcengine",
+	Alias:    []string{"volcengine", "volc", "vke"},
+	HomePage: "https://console.volcengine.com/vke",
+	Service:  "VKE",
+	Credentials: []Credential{
+		{Env: "VOLCENGINE_ACCESS_KEY", Prompt: "Volcengine Access Key ID"},
+		{Env: "VOLCENGINE_SECRET_KEY", Prompt: "Volcengine Secret Access Key"},
+		{Env: volcengineValidHoursEnv, Optional: true},
+	},
+	Regional: true,
+	// the regions are not listed by an api, any region is accepted by --region_id
+	RegionPrompt:  "Select Region ID (the list may be incomplete, use --region_id for another region)",
+	ContextPrefix: "volcengine",
+	New: func(s *Session) (Cluster, error) {
+		v := &Volcengine{
+			AccessKeyID:     s.Credentials["VOLCENGINE_ACCESS_KEY"],
+			AccessKeySecret: s.Credentials["VOLCENGINE_SECRET_KEY"],
+			RegionID:        s.RegionID,
+		}
+		if hours := s.Credentials[volcengineValidHoursEnv]; hours != "" {
+			var err error
+			if v.KubeconfigValidHours, err = strconv.Atoi(hours); err != nil || v.KubeconfigValidHours <= 0 {
+				return nil, fmt.Errorf("invalid %s %q, it is a number of hours", volcengineValidHoursEnv, hours)
+			}
+		}
+		return v, nil
+	},
+}
+
+// Volcengine struct of volcengine cloud
+type Volcengine struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	RegionID        string
+	// KubeconfigValidHours is the validity of the public kubeconfig created for a cluster without any,
+	// none is created when it is 0
+	KubeconfigValidHours int
+
+	endpoint string
+}
+
+// call call the action of the vke openapi signed with the AK/SK and decode its result into out
+func (v *Volcengine) call(action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := v.endpoint
+	if endpoint == "" {
+		endpoint = volcengineURL
+	}
+	query := url.Values{"Action": {action}, "Version": {volcengineVersion}}
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/?"+query.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	volcengineSign(req, body, v.AccessKeyID, v.AccessKeySecret, v.RegionID, volcengineService, time.Now())
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		ResponseMetadata struct {
+			Error *struct {
+				Code    string `json:"Code"`
+				Message string `json:"Message"`
+			} `json:"Error"`
+		} `json:"ResponseMetadata"`
+		Result json.RawMessage `json:"Result"`
+	}
+	if err = json.Unmarshal(content, &result); err != nil {
+		return fmt.Errorf("volcengine api error %d: %s", resp.StatusCode, strings.TrimSpace(string(content)))
+	}
+	if apiErr := result.ResponseMetadata.Error; apiErr != nil && apiErr.Code != "" {
+		return fmt.Errorf("volcengine api error %s: %s", apiErr.Code, apiErr.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("volcengine api error %d: %s", resp.StatusCode, strings.TrimSpace(string(content)))
+	}
+	return json.Unmarshal(result.Result, out)
+}
+
+// volcengineSign sign the request with the HMAC-SHA256 signature v4 of volcengine
+func volcengineSign(req *http.Request, body []byte, ak, sk, region, service string, now time.Time) {
+	date := now.UTC().Format("20060102T150405Z")
+	payloadHash := hashHex(body)
+	req.Header.Set("X-Date", date)
+	req.Header.Set("X-Content-Sha256", payloadHash)
+	signedHeaders, canonicalHeaders := canonicalHeaders(req, []string{"content-type", "host", "x-content-sha256", "x-date"})
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date[:8], region, service, "request"}, "/")
+	stringToSign := strings.Join([]string{volcengineAlgorithm, date, scope, hashHex([]byte(canonicalRequest))}, "\n")
+	key := []byte(sk)
+	for _, part := range []string{date[:8], region, service, "request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		volcengineAlgorithm, ak, scope, signedHeaders, signature))
+}
+
+// GetRegionID get region id of vke cluster, the known regions since vke has no api listing them,
+// the other ones are given by --region_id
+func (v *Volcengine) GetRegionID() ([]string, error) {
+	return []string{"cn-beijing", "cn-shanghai", "cn-guangzhou", "cn-hongkong", "ap-southeast-1", "ap-southeast-3"}, nil
+}
+
+// ListCluster list vke cluster info
+func (v *Volcengine) ListCluster() (clusters []ClusterInfo, err error) {
+	for page := 1; ; page++ {
+		var result struct {
+			Items []struct {
+				ID                string `json:"Id"`
+				Name              string `json:"Name"`
+				KubernetesVersion string `json:"KubernetesVersion"`
+			} `json:"Items"`
+			Total int `json:"Total"`
+		}
+		err = v.call("ListClusters", map[string]int{"PageNumber": page, "PageSize": volcenginePageSize}, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range result.Items {
+			clusters = append(clusters, ClusterInfo{
+				Name:       cluster.Name,
+				ID:         cluster.ID,
+				RegionID:   v.RegionID,
+				K8sVersion: cluster.KubernetesVersion,
+				ConsoleURL: "https://console.volcengine.com/vke",
+			})
+		}
+		if len(result.Items) < volcenginePageSize || len(clusters) >= result.Total {
+			return clusters, nil
+		}
+	}
+}
+
+// listKubeconfig return the public kubeconfig of the cluster, or the private one, empty when there is none
+func (v *Volcengine) listKubeconfig(clusterID string) (string, error) {
+	var result struct {
+		Items []struct {
+			Type       string `json:"Type"`
+			Kubeconfig string `json:"Kubeconfig"`
+		} `json:"Items"`
+	}
+	err := v.call("ListKubeconfigs", map[string]interface{}{
+		"Filter":   map[string][]string{"ClusterIds": {clusterID}, "Types": {"Public", "Private"}},
+		"PageSize": volcenginePageSize,
+	}, &result)
+	if err != nil {
+		return "", err
+	}
+	kubeconfig := ""
+	for _, item := range result.Items {
+		if kubeconfig == "" || item.Type == "Public" {
+			kubeconfig = item.Kubeconfig
+		}
+	}
+	return kubeconfig, nil
+}
+
+// GetKubeConfig get vke kubeConfig file, a public one valid for KubeconfigValidHours is created when the
+// cluster has none
+func (v *Volcengine) GetKubeConfig(clusterID string) (string, error) {
+	kubeconfig, err := v.listKubeconfig(clusterID)
+	if err != nil {
+		return "", err
+	}
+	if kubeconfig == "" {
+		// a kubeconfig is a credential of the account, it is only created when asked for
+		if v.KubeconfigValidHours == 0 {
+			return "", fmt.Errorf("cluster %s has no kubeconfig, create one in the console, or set %s "+
+				"to create a public one valid for that many hours", clusterID, volcengineValidHoursEnv)
+		}
+		var created struct {
+			ID string `json:"Id"`
+		}
+		err = v.call("CreateKubeconfig", map[string]interface{}{
+			"ClusterId":     clusterID,
+			"Type":          "Public",
+			"ValidDuration": v.KubeconfigValidHours,
+		}, &created)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(os.Stderr, "Created the public kubeconfig %s of cluster %s, valid for %d hours\n",
+			created.ID, clusterID, v.KubeconfigValidHours)
+		if kubeconfig, err = v.listKubeconfig(clusterID); err != nil {
+			return "", err
+		}
+		if kubeconfig == "" {
+			return "", fmt.Errorf("no kubeconfig found for cluster %s", clusterID)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,119 @@
+package cloud
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFakeVolcengine(t *testing.T, clusters int) (*Volcengine, *[]string) {
+	var actions []string
+	kubeconfigs := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifySignature(t, r, func(req *http.Request, body []byte, now time.Time) {
+			volcengineSign(req, body, "volc-ak", "volc-sk", "cn-beijing", "vke", now)
+		}, "X-Date") {
+			fmt.Fprint(w, `{"ResponseMetadata":{"Error":{"Code":"SignatureDoesNotMatch","Message":"The request signature does not match"}}}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		action := r.URL.Query().Get("Action")
+		actions = append(actions, action)
+		var in struct {
+			PageNumber    int
+			ClusterID     string `json:"ClusterId"`
+			ValidDuration int
+			Filter        struct{ ClusterIds []string }
+		}
+		_ = json.Unmarshal(body, &in)
+		var result interface{}
+		switch action {
+		case "ListClusters":
+			var items []map[string]string
+			for i := (in.PageNumber - 1) * volcenginePageSize; i < clusters && i < in.PageNumber*volcenginePageSize; i++ {
+				items = append(items, map[string]string{"Id": fmt.Sprintf("c-%d", i), "Name": fmt.Sprintf("cluster-%d", i), "KubernetesVersion": "v1.28.3-vke.13"})
+			}
+			result = map[string]interface{}{"Items": items, "Total": clusters}
+		case "ListKubeconfigs":
+			var items []map[string]string
+			if kubeconfig, ok := kubeconfigs[in.Filter.ClusterIds[0]]; ok {
+				items = append(items, map[string]string{"Type": "Private", "Kubeconfig": base64.StdEncoding.EncodeToString([]byte("private"))},
+					map[string]string{"Type": "Public", "Kubeconfig": base64.StdEncoding.EncodeToString([]byte(kubeconfig))})
+			}
+			result = map[string]interface{}{"Items": items}
+		case "CreateKubeconfig":
+			if in.ValidDuration != 24 {
+				http.Error(w, "unexpected ValidDuration", http.StatusBadRequest)
+				return
+			}
+			kubeconfigs[in.ClusterID] = fmt.Sprintf("kubeconfig of %s", in.ClusterID)
+			result = map[string]string{"Id": "kc-1"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ResponseMetadata": map[string]string{"Action": action}, "Result": result})
+	}))
+	t.Cleanup(server.Close)
+	return &Volcengine{AccessKeyID: "volc-ak", AccessKeySecret: "volc-sk", RegionID: "cn-beijing", endpoint: server.URL}, &actions
+}
+
+func TestVolcengine_ListCluster(t *testing.T) {
+	volc, actions := newFakeVolcengine(t, 150)
+	clusters, err := volc.ListCluster()
+	if err != nil {
+		t.Fatalf("ListCluster() error = %v", err)
+	}
+	if len(clusters) != 150 || len(*actions) != 2 {
+		t.Fatalf("ListCluster() got %d clusters in %d calls", len(clusters), len(*actions))
+	}
+	if clusters[149].ID != "c-149" || clusters[149].RegionID != "cn-beijing" || clusters[149].K8sVersion != "v1.28.3-vke.13" {
+		t.Errorf("ListCluster() got = %+v", clusters[149])
+	}
+
+	volc.AccessKeySecret = "wrong"
+	if _, err = volc.ListCluster(); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("ListCluster() with a wrong secret error = %v", err)
+	}
+}
+
+func TestVolcengine_GetKubeConfig(t *testing.T) {
+	volc, actions := newFakeVolcengine(t, 1)
+	// no kubeconfig is created without opting in
+	if _, err := volc.GetKubeConfig("c-0"); err == nil || !strings.Contains(err.Error(), volcengineValidHoursEnv) {
+		t.Errorf("GetKubeConfig() of a cluster without kubeconfig error = %v", err)
+	}
+	if strings.Join(*actions, ",") != "ListKubeconfigs" {
+		t.Errorf("GetKubeConfig() without opting in called %v", *actions)
+	}
+	*actions = nil
+	volc.KubeconfigValidHours = 24
+	kubeconfig, err := volc.GetKubeConfig("c-0")
+	if err != nil || kubeconfig != "kubeconfig of c-0" {
+		t.Fatalf("GetKubeConfig() got = %q, error = %v", kubeconfig, err)
+	}
+	want := "ListKubeconfigs,CreateKubeconfig,ListKubeconfigs"
+	if strings.Join(*actions, ",") != want {
+		t.Errorf("GetKubeConfig() called %v, want %s", *actions, want)
+	}
+	// the existing public kubeconfig is reused
+	*actions = nil
+	if kubeconfig, err = volc.GetKubeConfig("c-0"); err != nil || kubeconfig != "kubeconfig of c-0" || len(*actions) != 1 {
+		t.Errorf("GetKubeConfig() again got = %q in %v, error = %v", kubeconfig, *actions, err)
+	}
+}
+
+func TestVolcengine_New(t *testing.T) {
+	session := &Session{Credentials: map[string]string{volcengineValidHoursEnv: "24"}}
+	client, err := volcengineProvider.New(session)
+	if err != nil || client.(*Volcengine).KubeconfigValidHours != 24 {
+		t.Errorf("New() got %+v, error = %v", client, err)
+	}
+	session.Credentials[volcengineValidHoursEnv] = "3y"
+	if _, err = volcengineProvider.New(session); err == nil {
+		t.Errorf("New() should reject an invalid %s", volcengineValidHoursEnv)
+	}
+}
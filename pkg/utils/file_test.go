@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config")
+	if err := WriteFileAtomic(file, []byte("first"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(file, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(link, []byte("second"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	content, _ := os.ReadFile(file)
+	if string(content) != "second" {
+		t.Errorf("WriteFileAtomic() content = %q, want %q", content, "second")
+	}
+	info, _ := os.Lstat(link)
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("WriteFileAtomic() replaced the symlink")
+	}
+	info, _ = os.Stat(file)
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("WriteFileAtomic() mode = %v, want %v", info.Mode().Perm(), os.FileMode(0644))
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("WriteFileAtomic() left temp files behind: %v", entries)
+	}
+}
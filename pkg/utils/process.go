@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"os/exec"
+)
+
+// StartDetached start the command in its own session, so it keeps running after kubecm exits
+// and does not receive the signals of the terminal
+func StartDetached(cmd *exec.Cmd) error {
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}